@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"dbv/pkg/config"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// envPrefix prefixes the environment variables that override config file
+// keys, e.g. DBV_OUTPUT_FORMAT for output.format.
+const envPrefix = "DBV"
+
+// flagKeys maps config file keys to the flags that override them.
+var flagKeys = map[string]string{
+	"profile":                  "profile",
+	"history.secret_source":    "secret-source",
+	"database.ssh.host":        "ssh-host",
+	"database.ssh.user":        "ssh-user",
+	"database.ssh.key_file":    "ssh-key",
+	"database.ssh.known_hosts": "ssh-known-hosts",
+	"database.ssh.jump":        "ssh-jump",
+	"output.format":            "format",
+	"output.file":              "output",
+	"output.options":           "format-opt",
+	"schema.include_views":     "include-views",
+	"schema.exclude_tables":    "exclude-tables",
+	"schema.include_tables":    "include-tables",
+	"schema.schemas":           "schemas",
+	"schema.parallelism":       "parallelism",
+	"schema.include_columns":   "include-columns",
+	"schema.exclude_columns":   "exclude-columns",
+	"schema.redaction":         "redaction",
+	"focus.tables":             "focus",
+	"focus.depth":              "depth",
+	"focus.direction":          "focus-direction",
+	"focus.collapse_boundary":  "collapse-boundary",
+}
+
+// loadConfig resolves the configuration with the precedence flags > DBV_*
+// environment variables > selected profile > config file top level > flag
+// defaults. An empty profile selects the one named by --profile, DBV_PROFILE
+// or the config file's profile key, if any.
+func loadConfig(cmd *cobra.Command, profile string) (AppConfig, error) {
+	v, err := newViper(cmd)
+	if err != nil {
+		return AppConfig{}, err
+	}
+
+	if profile == "" {
+		profile = v.GetString("profile")
+	}
+	if profile != "" {
+		settings, ok := v.Get("profiles." + profile).(map[string]any)
+		if !ok {
+			return AppConfig{}, fmt.Errorf("profile '%s' not found. Available profiles: %s",
+				profile, strings.Join(profileNames(v), ", "))
+		}
+		if err := v.MergeConfigMap(settings); err != nil {
+			return AppConfig{}, fmt.Errorf("profile '%s': %w", profile, err)
+		}
+	}
+
+	var cfg config.Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return AppConfig{}, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	appConfig := appConfigFrom(cfg.Profile)
+	appConfig.SecretSource = cfg.History.SecretSource
+	if cmd.Flags().Changed("database-url") {
+		appConfig.DatabaseURLs, _ = cmd.Flags().GetStringArray("database-url")
+	}
+
+	return appConfig, nil
+}
+
+// loadProfiles resolves every profile of the config file, keyed by name.
+func loadProfiles(cmd *cobra.Command) (map[string]AppConfig, error) {
+	v, err := newViper(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make(map[string]AppConfig)
+	for _, name := range profileNames(v) {
+		appConfig, err := loadConfig(cmd, name)
+		if err != nil {
+			return nil, err
+		}
+		profiles[name] = appConfig
+	}
+
+	return profiles, nil
+}
+
+// newViper reads the config file and binds the environment and flags.
+func newViper(cmd *cobra.Command) (*viper.Viper, error) {
+	v := viper.New()
+
+	path, err := configFile(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+		}
+	}
+
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	// database.url has no single-valued flag; the repeatable --database-url
+	// flag replaces it in loadConfig.
+	if err := v.BindEnv("database.url"); err != nil {
+		return nil, err
+	}
+	for key, name := range flagKeys {
+		if err := v.BindPFlag(key, cmd.Flags().Lookup(name)); err != nil {
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
+
+func profileNames(v *viper.Viper) []string {
+	names := make([]string, 0)
+	for name := range v.GetStringMap("profiles") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// configFile returns the config file to read: --config when given, otherwise
+// the first of ./dbv.yaml and $XDG_CONFIG_HOME/dbv/config.yaml that exists.
+// It returns "" when there is none.
+func configFile(cmd *cobra.Command) (string, error) {
+	if path, _ := cmd.Flags().GetString("config"); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("config file: %w", err)
+		}
+		return path, nil
+	}
+
+	candidates := []string{"dbv.yaml"}
+	if dir, err := configDir(); err == nil {
+		candidates = append(candidates, filepath.Join(dir, "config.yaml"))
+	}
+
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("config file: %w", err)
+		}
+	}
+
+	return "", nil
+}
+
+// configDir returns dbv's directory under $XDG_CONFIG_HOME, falling back to
+// ~/.config when the variable is unset.
+func configDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "dbv"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "dbv"), nil
+}
+
+func appConfigFrom(cfg config.Profile) AppConfig {
+	appConfig := AppConfig{
+		SSH:           cfg.Database.SSH,
+		Format:        cfg.Output.Format,
+		FormatOptions: cfg.Output.Options,
+		OutputFile:    cfg.Output.File,
+		IncludeViews:  cfg.Schema.IncludeViews,
+		ExcludeTables: cfg.Schema.ExcludeTables,
+		IncludeTables: cfg.Schema.IncludeTables,
+		Schemas:       cfg.Schema.Schemas,
+		Parallelism:   cfg.Schema.Parallelism,
+
+		IncludeColumns: cfg.Schema.IncludeColumns,
+		ExcludeColumns: cfg.Schema.ExcludeColumns,
+		Redaction:      cfg.Schema.Redaction,
+
+		FocusTables:      cfg.Focus.Tables,
+		FocusDepth:       cfg.Focus.Depth,
+		FocusDirection:   cfg.Focus.Direction,
+		CollapseBoundary: cfg.Focus.CollapseBoundary,
+	}
+
+	if cfg.Database.URL != "" {
+		appConfig.DatabaseURLs = []string{cfg.Database.URL}
+	}
+
+	return appConfig
+}
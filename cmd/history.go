@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"dbv/internal/secrets"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// maxHistory is the number of recent connections kept.
+const maxHistory = 10
+
+// ConnectionHistory lists recently used connection URLs, most recent first.
+// The URLs never contain passwords; those live in the configured
+// secrets.Store.
+type ConnectionHistory struct {
+	Recent []string `json:"recent"`
+}
+
+func getHistoryPath() string {
+	dir, err := configDir()
+	if err != nil {
+		return ".dbv_history.json"
+	}
+	return filepath.Join(dir, "history.json")
+}
+
+// getLegacyHistoryPath is where dbv used to keep its history, with
+// passwords in plain text.
+func getLegacyHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".dbv_history.json"
+	}
+	return filepath.Join(home, ".dbv_history.json")
+}
+
+func loadHistory(store secrets.Store) ConnectionHistory {
+	var history ConnectionHistory
+
+	data, err := os.ReadFile(getHistoryPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return migrateHistory(store)
+	}
+	if err != nil {
+		return history
+	}
+
+	json.Unmarshal(data, &history)
+	return history
+}
+
+// migrateHistory moves the legacy history file to the config directory,
+// handing its passwords to store, and removes the plain-text original. The
+// secrets that store does not keep, such as every one with the none source,
+// are listed on stderr, since they are gone with the original.
+func migrateHistory(store secrets.Store) ConnectionHistory {
+	var legacy, history ConnectionHistory
+
+	data, err := os.ReadFile(getLegacyHistoryPath())
+	if err != nil {
+		return history
+	}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return history
+	}
+
+	var dropped []string
+	for _, conn := range legacy.Recent {
+		stripped, err := secrets.Save(store, conn)
+		if err != nil {
+			return ConnectionHistory{}
+		}
+		lost, err := droppedSecrets(store, conn, stripped)
+		if err != nil {
+			return ConnectionHistory{}
+		}
+		for _, name := range lost {
+			dropped = append(dropped, fmt.Sprintf("%s of %s", name, secrets.Mask(conn)))
+		}
+		if !contains(history.Recent, stripped) {
+			history.Recent = append(history.Recent, stripped)
+		}
+	}
+
+	if err := writeHistory(history); err != nil {
+		return history
+	}
+	if err := os.Remove(getLegacyHistoryPath()); err != nil {
+		return history
+	}
+
+	if len(dropped) > 0 {
+		fmt.Fprintf(os.Stderr, "%s moved %s to %s; these secrets were not kept by the secret source and must be entered again:\n",
+			styles.error.Render("!"), getLegacyHistoryPath(), getHistoryPath())
+		for _, secret := range dropped {
+			fmt.Fprintf(os.Stderr, "  %s\n", secret)
+		}
+	}
+
+	return history
+}
+
+// droppedSecrets returns the names of the secrets of conn that store cannot
+// restore into stripped.
+func droppedSecrets(store secrets.Store, conn, stripped string) ([]string, error) {
+	_, saved := secrets.Strip(conn)
+	restored, err := secrets.Restore(store, stripped)
+	if err != nil {
+		return nil, err
+	}
+	_, kept := secrets.Strip(restored)
+
+	var dropped []string
+	for _, name := range []string{secrets.Password, secrets.SSLPassword} {
+		if _, ok := saved[name]; ok && kept[name] != saved[name] {
+			dropped = append(dropped, name)
+		}
+	}
+	return dropped, nil
+}
+
+// saveToHistory records connectionURL as the most recent connection. Its
+// password, if any, goes to store instead of the history file.
+func saveToHistory(store secrets.Store, connectionURL string) error {
+	history := loadHistory(store)
+
+	connectionURL, err := secrets.Save(store, connectionURL)
+	if err != nil {
+		return err
+	}
+
+	for i, conn := range history.Recent {
+		if conn == connectionURL {
+			history.Recent = append(history.Recent[:i], history.Recent[i+1:]...)
+			break
+		}
+	}
+
+	history.Recent = append([]string{connectionURL}, history.Recent...)
+
+	if len(history.Recent) > maxHistory {
+		history.Recent = history.Recent[:maxHistory]
+	}
+
+	return writeHistory(history)
+}
+
+// writeHistory writes the history file readable by its owner only, since
+// connection URLs still reveal hosts and user names.
+func writeHistory(history ConnectionHistory) error {
+	path := getHistoryPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Chmod(path, 0600)
+}
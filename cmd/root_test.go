@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func docsSchema(tables ...string) *schema.Schema {
+	s := &schema.Schema{Database: "sqlite"}
+	for _, name := range tables {
+		s.Tables = append(s.Tables, schema.Table{
+			Name: name, Schema: "main", PrimaryKeys: []string{"id"},
+			Columns: []schema.Column{{Name: "id", Type: "INTEGER", IsPrimaryKey: true}},
+		})
+	}
+	return s
+}
+
+// listFiles returns the files under dir, relative to it.
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func docsGenerator(t *testing.T, markup string) generator.MultiFileGenerator {
+	t.Helper()
+	gen, err := generator.New("docs", generator.Options{"markup": markup, "diagrams": "false"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gen.(generator.MultiFileGenerator)
+}
+
+func TestWriteFilesRemovesStalePages(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "site")
+	gen := docsGenerator(t, "html")
+
+	if err := writeFiles(dir, gen, docsSchema("orders", "users")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.html"), []byte("kept"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFiles(dir, gen, docsSchema("users")); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := os.Stat(dir); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0755 {
+		t.Errorf("site directory mode = %v, want 0755", info.Mode().Perm())
+	}
+
+	got := listFiles(t, dir)
+	want := []string{manifestName, "index.html", "notes.html", "style.css", "tables/users.html"}
+	if !slices.Equal(got, want) {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+
+	// No temporary directory is left next to the site.
+	entries, err := os.ReadDir(filepath.Dir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("output parent has %d entries, want only the site", len(entries))
+	}
+}
+
+func TestWriteFilesKeepsForeignFiles(t *testing.T) {
+	// The site is generated into a directory that already has hand-written
+	// pages, some next to where dbv puts its own.
+	dir := filepath.Join(t.TempDir(), "docs")
+	foreign := []string{"guide/intro.md", "tables/custom.md", "README.md"}
+	for _, name := range foreign {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("hand-written"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gen := docsGenerator(t, "markdown")
+	for _, tables := range [][]string{{"orders", "users"}, {"users"}, {}} {
+		if err := writeFiles(dir, gen, docsSchema(tables...)); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range foreign {
+			if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name))); err != nil || string(data) != "hand-written" {
+				t.Errorf("after generating %v, %s = %q, %v", tables, name, data, err)
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tables", "users.md")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the page of the dropped users table is still there: %v", err)
+	}
+
+	// A manifest that points outside the directory is not followed.
+	outside := filepath.Join(filepath.Dir(dir), "outside.md")
+	if err := os.WriteFile(outside, []byte("outside"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestName), []byte("../outside.md\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFiles(dir, gen, docsSchema("users")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("a file outside the output directory was removed: %v", err)
+	}
+}
+
+// failingGenerator writes a page and then fails.
+type failingGenerator struct {
+	generator.MultiFileGenerator
+}
+
+func (g failingGenerator) GenerateFiles(create generator.FileCreator, s *schema.Schema) error {
+	f, err := create("tables/new.html")
+	if err != nil {
+		return err
+	}
+	io.WriteString(f, "partial")
+	return errors.New("boom")
+}
+
+func TestWriteFilesKeepsSiteOnFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "site")
+	gen := docsGenerator(t, "markdown")
+	if err := writeFiles(dir, gen, docsSchema("users")); err != nil {
+		t.Fatal(err)
+	}
+	before := listFiles(t, dir)
+
+	if err := writeFiles(dir, failingGenerator{gen}, docsSchema("users")); err == nil {
+		t.Fatal("writeFiles() returned no error")
+	}
+	if after := listFiles(t, dir); !slices.Equal(before, after) {
+		t.Errorf("files after a failed run = %v, want %v", after, before)
+	}
+}
+
+func TestSplitParallelism(t *testing.T) {
+	tests := []struct {
+		parallelism, databases int
+		concurrent, each       int
+	}{
+		{8, 1, 1, 8},
+		{8, 2, 2, 4},
+		{8, 3, 3, 2},
+		{2, 5, 2, 1},
+		{1, 3, 1, 1},
+		{0, 1, 1, 4},
+	}
+	for _, tt := range tests {
+		concurrent, each := splitParallelism(tt.parallelism, tt.databases)
+		if concurrent != tt.concurrent || each != tt.each {
+			t.Errorf("splitParallelism(%d, %d) = %d, %d, want %d, %d",
+				tt.parallelism, tt.databases, concurrent, each, tt.concurrent, tt.each)
+		}
+		if concurrent*each > max(tt.parallelism, 4) {
+			t.Errorf("splitParallelism(%d, %d) allows %d queries", tt.parallelism, tt.databases, concurrent*each)
+		}
+	}
+}
@@ -2,11 +2,11 @@ package database
 
 import (
 	"database/sql"
-	"dbv/internal/schema"
 	"dbv/pkg/config"
+	"dbv/pkg/extractor"
+	"dbv/pkg/filter"
+	"dbv/pkg/schema"
 	"fmt"
-	"net/url"
-	"strings"
 
 	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
@@ -14,16 +14,41 @@ import (
 )
 
 type Connector struct {
-	db     *sql.DB
-	driver string
+	db          *sql.DB
+	driver      string
+	databaseURL string
+	tunnel      *sshTunnel
 }
 
-type SchemaExtractor interface {
-	ExtractSchema(cfg config.SchemaConfig) (*schema.Schema, error)
+// NewConnector opens and pings the database. When tunnel names an SSH host,
+// the connection is made through an in-process SSH tunnel that lives until
+// Close.
+func NewConnector(databaseURL string, tunnel config.SSHConfig) (*Connector, error) {
+	connectURL := databaseURL
+	var t *sshTunnel
+	if tunnel.Host != "" {
+		var err error
+		connectURL, t, err = openTunnel(databaseURL, tunnel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open ssh tunnel: %w", err)
+		}
+	}
+
+	c, err := connect(connectURL)
+	if err != nil {
+		if t != nil {
+			t.Close()
+		}
+		return nil, err
+	}
+	c.databaseURL = databaseURL
+	c.tunnel = t
+
+	return c, nil
 }
 
-func NewConnector(databaseURL string) (*Connector, error) {
-	driver, dsn, err := ParseDatabaseURL(databaseURL) // Updated call
+func connect(databaseURL string) (*Connector, error) {
+	driver, dsn, err := ParseDatabaseURL(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse database URL: %w", err)
 	}
@@ -34,6 +59,7 @@ func NewConnector(databaseURL string) (*Connector, error) {
 	}
 
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
@@ -44,44 +70,41 @@ func NewConnector(databaseURL string) (*Connector, error) {
 }
 
 func (c *Connector) Close() error {
-	return c.db.Close()
+	err := c.db.Close()
+	if c.tunnel != nil {
+		if tunnelErr := c.tunnel.Close(); err == nil {
+			err = tunnelErr
+		}
+	}
+	return err
 }
 
 func (c *Connector) ExtractSchema(cfg config.SchemaConfig) (*schema.Schema, error) {
-	var extractor SchemaExtractor
-
-	switch c.driver {
-	case "postgres":
-		extractor = &PostgreSQLExtractor{db: c.db}
-	// case "mysql":
-	//     extractor = &MySQLExtractor{db: c.db}
-	case "sqlite3":
-		extractor = &SQLiteExtractor{db: c.db}
-	default:
-		return nil, fmt.Errorf("unsupported database driver: %s", c.driver)
+	if cfg.Parallelism < 1 {
+		cfg.Parallelism = DefaultParallelism
 	}
+	c.db.SetMaxOpenConns(cfg.Parallelism)
+	c.db.SetMaxIdleConns(cfg.Parallelism)
 
-	return extractor.ExtractSchema(cfg)
-}
+	ext, err := extractor.New(c.driver, c.db, c.databaseURL)
+	if err != nil {
+		return nil, err
+	}
 
-func ParseDatabaseURL(databaseURL string) (driver, dsn string, err error) {
-	u, err := url.Parse(databaseURL)
+	s, err := ext.ExtractSchema(cfg)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	switch u.Scheme {
-	case "postgres", "postgresql":
-		return "postgres", databaseURL, nil
-	// case "mysql":
-	//     dsn = fmt.Sprintf("%s@tcp(%s)%s", u.User.String(), u.Host, u.Path)
-	//     if u.RawQuery != "" {
-	//         dsn += "?" + u.RawQuery
-	//     }
-	//     return "mysql", dsn, nil
-	case "sqlite", "sqlite3":
-		return "sqlite3", strings.TrimPrefix(databaseURL, "sqlite://"), nil
-	default:
-		return "", "", fmt.Errorf("unsupported database scheme: %s", u.Scheme)
+	if err := filter.Columns(s, cfg); err != nil {
+		return nil, err
 	}
+
+	return s, nil
+}
+
+// ParseDatabaseURL resolves a database URL to a database/sql driver name and
+// DSN using the schemes registered with the extractor package.
+func ParseDatabaseURL(databaseURL string) (driver, dsn string, err error) {
+	return extractor.Resolve(databaseURL)
 }
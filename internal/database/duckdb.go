@@ -0,0 +1,399 @@
+//go:build duckdb
+
+package database
+
+import (
+	"database/sql"
+	"dbv/pkg/config"
+	"dbv/pkg/extractor"
+	"dbv/pkg/filter"
+	"dbv/pkg/schema"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "github.com/marcboeker/go-duckdb"
+)
+
+// DuckDBExtractor reads .duckdb files through DuckDB's metadata table
+// functions. The driver embeds DuckDB, so it is only compiled in with the
+// duckdb build tag to keep the default binary small.
+type DuckDBExtractor struct {
+    db *sql.DB
+}
+
+func init() {
+    extractor.Register("duckdb", func(db *sql.DB, _ string) (extractor.SchemaExtractor, error) {
+        return &DuckDBExtractor{db: db}, nil
+    })
+    extractor.RegisterScheme("duckdb", "duckdb", duckDBDSN)
+}
+
+// duckDBDSN opens the file read-only and disables extension autoloading so
+// extraction never writes to the file or reaches the network.
+func duckDBDSN(u *url.URL, databaseURL string) (string, error) {
+    path, err := extractor.TrimScheme(u, databaseURL)
+    if err != nil {
+        return "", err
+    }
+    path, _, _ = strings.Cut(path, "?")
+
+    params := u.Query()
+    if params.Get("access_mode") == "" {
+        params.Set("access_mode", "read_only")
+    }
+    params.Set("autoinstall_known_extensions", "false")
+    params.Set("autoload_known_extensions", "false")
+
+    return path + "?" + params.Encode(), nil
+}
+
+func (d *DuckDBExtractor) ExtractSchema(cfg config.SchemaConfig) (*schema.Schema, error) {
+    s := &schema.Schema{
+        Database:    "duckdb",
+        GeneratedAt: time.Now(),
+    }
+
+    schemas := cfg.Schemas
+    if len(schemas) == 0 {
+        schemas = []string{"main"}
+    }
+
+    f, err := filter.NewTables(cfg)
+    if err != nil {
+        return nil, err
+    }
+
+    tables, err := d.extractTables(cfg, f, schemas)
+    if err != nil {
+        return nil, err
+    }
+    s.Tables = tables
+
+    if cfg.IncludeViews {
+        views, err := d.extractViews(cfg, f, schemas)
+        if err != nil {
+            return nil, err
+        }
+        s.Views = views
+    }
+
+    foreignKeys, err := d.extractForeignKeys(f, schemas)
+    if err != nil {
+        return nil, err
+    }
+    s.ForeignKeys = foreignKeys
+
+    return s, nil
+}
+
+func (d *DuckDBExtractor) extractTables(cfg config.SchemaConfig, f *filter.Tables, schemas []string) ([]schema.Table, error) {
+    query := `
+        SELECT schema_name, table_name, COALESCE(comment, ''), estimated_size
+        FROM duckdb_tables()
+        WHERE database_name = current_database() AND NOT internal
+            AND schema_name IN (` + placeholders(len(schemas)) + `)
+        ORDER BY schema_name, table_name
+    `
+
+    rows, err := d.db.Query(query, stringArgs(schemas)...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var tables []schema.Table
+    for rows.Next() {
+        var table schema.Table
+        var estimate sql.NullInt64
+        if err := rows.Scan(&table.Schema, &table.Name, &table.Comment, &estimate); err != nil {
+            return nil, err
+        }
+        if estimate.Valid {
+            table.RowEstimate = &estimate.Int64
+        }
+
+        if !f.Allow(table.Schema, table.Name) {
+            continue
+        }
+
+        table.Type = "BASE TABLE"
+        tables = append(tables, table)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    err = forEach(len(tables), cfg.Parallelism, func(i int) error {
+        table := &tables[i]
+
+        columns, err := d.extractColumns(table.Schema, table.Name)
+        if err != nil {
+            return err
+        }
+
+        keys, err := d.extractKeys(table.Schema, table.Name)
+        if err != nil {
+            return err
+        }
+
+        for j := range columns {
+            col := &columns[j]
+            col.IsPrimaryKey = contains(keys.primary, col.Name)
+            col.IsUnique = contains(keys.unique, col.Name)
+        }
+        table.Columns = columns
+        table.PrimaryKeys = keys.primary
+
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return tables, nil
+}
+
+func (d *DuckDBExtractor) extractColumns(schemaName, tableName string) ([]schema.Column, error) {
+    query := `
+        SELECT
+            column_name,
+            data_type,
+            character_maximum_length,
+            numeric_precision,
+            numeric_scale,
+            is_nullable,
+            column_default,
+            COALESCE(comment, '')
+        FROM duckdb_columns()
+        WHERE database_name = current_database() AND schema_name = ? AND table_name = ?
+        ORDER BY column_index
+    `
+
+    rows, err := d.db.Query(query, schemaName, tableName)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var columns []schema.Column
+    for rows.Next() {
+        var col schema.Column
+        var length, precision, scale sql.NullInt64
+        var defaultValue sql.NullString
+
+        if err := rows.Scan(
+            &col.Name,
+            &col.Type,
+            &length,
+            &precision,
+            &scale,
+            &col.IsNullable,
+            &defaultValue,
+            &col.Comment,
+        ); err != nil {
+            return nil, err
+        }
+
+        if length.Valid {
+            l := int(length.Int64)
+            col.Length = &l
+        }
+        if precision.Valid {
+            p := int(precision.Int64)
+            col.Precision = &p
+        }
+        if scale.Valid {
+            s := int(scale.Int64)
+            col.Scale = &s
+        }
+        if defaultValue.Valid {
+            col.DefaultValue = &defaultValue.String
+        }
+
+        // DuckDB spells out modifiers such as DECIMAL(10,2) in data_type;
+        // keep the base name like information_schema does, since the
+        // precision and scale are already captured separately.
+        if precision.Valid && scale.Valid {
+            col.Type, _, _ = strings.Cut(col.Type, "(")
+        }
+
+        columns = append(columns, col)
+    }
+
+    return columns, rows.Err()
+}
+
+type duckDBKeys struct {
+    primary []string
+    unique  []string
+}
+
+// extractKeys returns the primary key columns and the columns covered by a
+// single-column UNIQUE constraint.
+func (d *DuckDBExtractor) extractKeys(schemaName, tableName string) (duckDBKeys, error) {
+    query := `
+        SELECT constraint_type, len(constraint_column_names), unnest(constraint_column_names)
+        FROM duckdb_constraints()
+        WHERE database_name = current_database() AND schema_name = ? AND table_name = ?
+            AND constraint_type IN ('PRIMARY KEY', 'UNIQUE')
+        ORDER BY constraint_index
+    `
+
+    var keys duckDBKeys
+
+    rows, err := d.db.Query(query, schemaName, tableName)
+    if err != nil {
+        return keys, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var constraintType, columnName string
+        var columnCount int
+        if err := rows.Scan(&constraintType, &columnCount, &columnName); err != nil {
+            return keys, err
+        }
+
+        switch {
+        case constraintType == "PRIMARY KEY":
+            keys.primary = append(keys.primary, columnName)
+        case columnCount == 1:
+            keys.unique = append(keys.unique, columnName)
+        }
+    }
+
+    return keys, rows.Err()
+}
+
+func (d *DuckDBExtractor) extractViews(cfg config.SchemaConfig, f *filter.Tables, schemas []string) ([]schema.View, error) {
+    query := `
+        SELECT schema_name, view_name, COALESCE(sql, ''), COALESCE(comment, '')
+        FROM duckdb_views()
+        WHERE database_name = current_database() AND NOT internal
+            AND schema_name IN (` + placeholders(len(schemas)) + `)
+        ORDER BY schema_name, view_name
+    `
+
+    rows, err := d.db.Query(query, stringArgs(schemas)...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var views []schema.View
+    for rows.Next() {
+        var view schema.View
+        if err := rows.Scan(&view.Schema, &view.Name, &view.Definition, &view.Comment); err != nil {
+            return nil, err
+        }
+
+        if !f.Allow(view.Schema, view.Name) {
+            continue
+        }
+
+        views = append(views, view)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    err = forEach(len(views), cfg.Parallelism, func(i int) error {
+        columns, err := d.extractColumns(views[i].Schema, views[i].Name)
+        if err != nil {
+            return err
+        }
+        views[i].Columns = columns
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return views, nil
+}
+
+// duckDBConstraint identifies a constraint by its table and its position in
+// the table.
+type duckDBConstraint struct {
+    schema, table string
+    index         int
+}
+
+// extractForeignKeys unnests the column lists of each FOREIGN KEY constraint
+// pairwise, producing one schema.ForeignKey per column pair. DuckDB does not
+// support referential actions, so OnUpdate and OnDelete are NO ACTION.
+func (d *DuckDBExtractor) extractForeignKeys(f *filter.Tables, schemas []string) ([]schema.ForeignKey, error) {
+    query := `
+        SELECT
+            COALESCE(constraint_name, ''),
+            constraint_index,
+            schema_name,
+            table_name,
+            unnest(constraint_column_names),
+            referenced_table,
+            unnest(referenced_column_names)
+        FROM duckdb_constraints()
+        WHERE database_name = current_database() AND constraint_type = 'FOREIGN KEY'
+            AND schema_name IN (` + placeholders(len(schemas)) + `)
+        ORDER BY schema_name, table_name, constraint_index
+    `
+
+    rows, err := d.db.Query(query, stringArgs(schemas)...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var foreignKeys []schema.ForeignKey
+    names := make(map[duckDBConstraint]string)
+    for rows.Next() {
+        var fk schema.ForeignKey
+        var index int
+        if err := rows.Scan(
+            &fk.Name,
+            &index,
+            &fk.Schema,
+            &fk.Table,
+            &fk.Column,
+            &fk.ReferencedTable,
+            &fk.ReferencedColumn,
+        ); err != nil {
+            return nil, err
+        }
+
+        // Unnamed keys are named after their first column, like SQLite's,
+        // and told apart by their position in the table.
+        key := duckDBConstraint{fk.Schema, fk.Table, index}
+        if fk.Name == "" {
+            if _, ok := names[key]; !ok {
+                names[key] = "fk_" + fk.Table + "_" + fk.Column
+            }
+            fk.Name = names[key]
+        }
+        fk.Ordinal = index + 1
+        fk.ReferencedSchema = fk.Schema
+        fk.OnUpdate = "NO ACTION"
+        fk.OnDelete = "NO ACTION"
+
+        if !f.AllowForeignKey(fk) {
+            continue
+        }
+
+        foreignKeys = append(foreignKeys, fk)
+    }
+
+    return foreignKeys, rows.Err()
+}
+
+func placeholders(n int) string {
+    return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func stringArgs(values []string) []any {
+    args := make([]any, len(values))
+    for i, v := range values {
+        args[i] = v
+    }
+    return args
+}
@@ -0,0 +1,90 @@
+//go:build duckdb
+
+package database
+
+import (
+	"database/sql"
+	"dbv/pkg/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDuckDBExtractSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.duckdb")
+
+	db, err := sql.Open("duckdb", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements := []string{
+		`CREATE SCHEMA staging`,
+		`CREATE TABLE accounts (id INTEGER, region VARCHAR, name VARCHAR NOT NULL UNIQUE, PRIMARY KEY (id, region))`,
+		`CREATE TABLE orders (
+            id INTEGER PRIMARY KEY,
+            account_id INTEGER,
+            account_region VARCHAR,
+            total DECIMAL(10, 2),
+            FOREIGN KEY (account_id, account_region) REFERENCES accounts (id, region)
+        )`,
+		`CREATE TABLE staging.raw (payload VARCHAR)`,
+		`CREATE VIEW big_orders AS SELECT * FROM orders WHERE total > 100`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewConnector("duckdb://"+path, config.SSHConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := c.ExtractSchema(config.SchemaConfig{Schemas: []string{"main"}, IncludeViews: true})
+	c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tables []string
+	for _, table := range s.Tables {
+		tables = append(tables, table.Schema+"."+table.Name)
+		if table.Name == "accounts" && !reflect.DeepEqual(table.PrimaryKeys, []string{"id", "region"}) {
+			t.Errorf("accounts primary keys = %v, want [id region]", table.PrimaryKeys)
+		}
+	}
+	if want := []string{"main.accounts", "main.orders"}; !reflect.DeepEqual(tables, want) {
+		t.Errorf("tables = %v, want %v", tables, want)
+	}
+	if len(s.Views) != 1 || s.Views[0].Name != "big_orders" {
+		t.Errorf("views = %+v, want big_orders", s.Views)
+	}
+
+	var pairs [][2]string
+	for _, fk := range s.ForeignKeys {
+		if fk.Table != "orders" || fk.ReferencedTable != "accounts" || fk.Ordinal != s.ForeignKeys[0].Ordinal || fk.Name != s.ForeignKeys[0].Name {
+			t.Errorf("unexpected foreign key %+v", fk)
+		}
+		pairs = append(pairs, [2]string{fk.Column, fk.ReferencedColumn})
+	}
+	if want := [][2]string{{"account_id", "id"}, {"account_region", "region"}}; !reflect.DeepEqual(pairs, want) {
+		t.Errorf("column pairs = %v, want %v", pairs, want)
+	}
+
+	// The file is opened read-only.
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(before, after) {
+		t.Error("extraction modified the database file")
+	}
+}
@@ -0,0 +1,26 @@
+package database
+
+import "golang.org/x/sync/errgroup"
+
+// DefaultParallelism is the number of concurrent queries used when the
+// schema configuration does not specify one.
+const DefaultParallelism = 4
+
+// forEach calls fn for every index in [0, n) using at most parallelism
+// goroutines. Callers write results into index-addressed slots so output
+// order does not depend on scheduling. The first error is returned.
+func forEach(n, parallelism int, fn func(i int) error) error {
+	if parallelism < 1 {
+		parallelism = DefaultParallelism
+	}
+
+	var g errgroup.Group
+	g.SetLimit(parallelism)
+	for i := 0; i < n; i++ {
+		g.Go(func() error {
+			return fn(i)
+		})
+	}
+
+	return g.Wait()
+}
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	pgServiceMu  sync.Mutex
+	pgServiceEnv = make(map[string]string)
+)
+
+// takePgServiceEnv moves PGSERVICE, PGSERVICEFILE and PGSYSCONFDIR out of the
+// environment and returns every value taken so far. lib/pq does not support
+// these variables and panics when it finds one while opening a connection, so
+// postgresDSN, which runs before every sql.Open of a postgres:// URL, takes
+// them and applies them itself. Taken values are kept for the connections
+// opened after the first one.
+func takePgServiceEnv() map[string]string {
+	pgServiceMu.Lock()
+	defer pgServiceMu.Unlock()
+
+	for _, name := range []string{"PGSERVICE", "PGSERVICEFILE", "PGSYSCONFDIR"} {
+		if value, ok := os.LookupEnv(name); ok {
+			pgServiceEnv[name] = value
+			os.Unsetenv(name)
+		}
+	}
+
+	env := make(map[string]string, len(pgServiceEnv))
+	for name, value := range pgServiceEnv {
+		env[name] = value
+	}
+	return env
+}
+
+// postgresDSN turns a postgres:// URL into a key=value DSN, resolving
+// settings the way libpq does: values in the URL win, then the service named
+// by ?service= or PGSERVICE from pg_service.conf, then the PG* environment
+// variables, which lib/pq applies to whatever is still unset. Without a
+// password, lib/pq falls back to PGPASSWORD and then ~/.pgpass. All of
+// these work with URLs as bare as postgres:// or postgresql:///?service=name.
+func postgresDSN(u *url.URL, _ string) (string, error) {
+	params := make(map[string]string)
+
+	env := takePgServiceEnv()
+	query := u.Query()
+	service := query.Get("service")
+	if service == "" {
+		service = env["PGSERVICE"]
+	}
+	if service != "" {
+		settings, err := lookupPgService(service, env)
+		if err != nil {
+			return "", err
+		}
+		for key, value := range settings {
+			params[key] = value
+		}
+	}
+
+	if host := u.Hostname(); host != "" {
+		params["host"] = host
+	}
+	if port := u.Port(); port != "" {
+		params["port"] = port
+	}
+	if dbname := strings.TrimPrefix(u.Path, "/"); dbname != "" {
+		params["dbname"] = dbname
+	}
+	if u.User != nil {
+		if user := u.User.Username(); user != "" {
+			params["user"] = user
+		}
+		if password, ok := u.User.Password(); ok {
+			params["password"] = password
+		}
+	}
+	for key, values := range query {
+		if key != "service" && len(values) > 0 {
+			params[key] = values[len(values)-1]
+		}
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+quoteDSNValue(params[key]))
+	}
+
+	return strings.Join(pairs, " "), nil
+}
+
+// quoteDSNValue quotes a key=value DSN value when it is empty or contains
+// spaces, quotes or backslashes.
+func quoteDSNValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// pgServiceFiles returns the service files libpq consults, in order: the
+// user's file ($PGSERVICEFILE or ~/.pg_service.conf), then the system-wide
+// pg_service.conf in $PGSYSCONFDIR or the usual system locations. env holds
+// the variables taken by takePgServiceEnv.
+func pgServiceFiles(env map[string]string) []string {
+	var files []string
+
+	if path := env["PGSERVICEFILE"]; path != "" {
+		files = append(files, path)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".pg_service.conf"))
+	}
+
+	if dir := env["PGSYSCONFDIR"]; dir != "" {
+		files = append(files, filepath.Join(dir, "pg_service.conf"))
+	} else {
+		files = append(files, "/etc/pg_service.conf", "/etc/postgresql-common/pg_service.conf")
+	}
+
+	return files
+}
+
+// lookupPgService returns the settings of the named service from the first
+// service file that defines it.
+func lookupPgService(name string, env map[string]string) (map[string]string, error) {
+	for _, path := range pgServiceFiles(env) {
+		settings, found, err := readPgService(path, name)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			return settings, nil
+		}
+	}
+
+	return nil, fmt.Errorf("definition of service %q not found", name)
+}
+
+// readPgService parses the INI-style service file at path and returns the
+// key=value settings of section [name].
+func readPgService(path, name string) (map[string]string, bool, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	settings := make(map[string]string)
+	found, inSection := false, false
+
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			if found {
+				break
+			}
+			inSection = strings.TrimSpace(line[1:len(line)-1]) == name
+			found = inSection
+			continue
+		}
+		if !inSection {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, false, fmt.Errorf("%s:%d: syntax error in service file", path, lineNo)
+		}
+		settings[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return settings, found, scanner.Err()
+}
@@ -0,0 +1,225 @@
+package database
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+const testServiceFile = `# user services
+[app]
+host=db.internal
+port = 6432
+dbname=app
+user=reader
+
+; the reporting replica
+[reports]
+host=replica.internal
+dbname=reports
+sslmode=require
+`
+
+// setPgServiceEnv points the service lookup at a temporary home and system
+// directory and sets the given variables. Variables taken from the
+// environment are forgotten when the test ends.
+func setPgServiceEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PGSYSCONFDIR", t.TempDir())
+	for _, name := range []string{"PGSERVICE", "PGSERVICEFILE"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+	t.Cleanup(func() {
+		pgServiceMu.Lock()
+		pgServiceEnv = make(map[string]string)
+		pgServiceMu.Unlock()
+	})
+}
+
+func writeServiceFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func dsn(t *testing.T, databaseURL string) string {
+	t.Helper()
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsn, err := postgresDSN(u, databaseURL)
+	if err != nil {
+		t.Fatalf("postgresDSN(%q) error = %v", databaseURL, err)
+	}
+	return dsn
+}
+
+func TestPostgresDSN(t *testing.T) {
+	serviceFile := writeServiceFile(t, t.TempDir(), "services.conf", testServiceFile)
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		url  string
+		want string
+	}{
+		{
+			name: "url only",
+			url:  "postgres://app:s3cret@db:5433/app?sslmode=disable",
+			want: "dbname=app host=db password=s3cret port=5433 sslmode=disable user=app",
+		},
+		{
+			name: "bare url",
+			url:  "postgres://",
+			want: "",
+		},
+		{
+			name: "quoted values",
+			url:  "postgres://app:it's%20me@db/app",
+			want: `dbname=app host=db password='it\'s me' user=app`,
+		},
+		{
+			name: "service from the url",
+			env:  map[string]string{"PGSERVICEFILE": serviceFile},
+			url:  "postgresql:///?service=app",
+			want: "dbname=app host=db.internal port=6432 user=reader",
+		},
+		{
+			name: "service from PGSERVICE",
+			env:  map[string]string{"PGSERVICEFILE": serviceFile, "PGSERVICE": "reports"},
+			url:  "postgres://",
+			want: "dbname=reports host=replica.internal sslmode=require",
+		},
+		{
+			name: "url beats the service",
+			env:  map[string]string{"PGSERVICEFILE": serviceFile, "PGSERVICE": "reports"},
+			url:  "postgres://admin@primary/other?service=app&sslmode=verify-full",
+			want: "dbname=other host=primary port=6432 sslmode=verify-full user=admin",
+		},
+		{
+			// lib/pq applies PG* variables only to settings missing from the
+			// DSN, so the service values must be in it.
+			name: "service beats PG* variables",
+			env:  map[string]string{"PGSERVICEFILE": serviceFile, "PGHOST": "env.internal", "PGUSER": "env"},
+			url:  "postgres:///?service=app",
+			want: "dbname=app host=db.internal port=6432 user=reader",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPgServiceEnv(t, tt.env)
+			if got := dsn(t, tt.url); got != tt.want {
+				t.Errorf("postgresDSN(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresDSNUnknownService(t *testing.T) {
+	setPgServiceEnv(t, map[string]string{
+		"PGSERVICEFILE": writeServiceFile(t, t.TempDir(), "services.conf", testServiceFile),
+	})
+	u, _ := url.Parse("postgres:///?service=nosuch")
+	if _, err := postgresDSN(u, u.String()); err == nil || !strings.Contains(err.Error(), `"nosuch" not found`) {
+		t.Errorf("postgresDSN() error = %v, want a service not found error", err)
+	}
+}
+
+func TestTakePgServiceEnv(t *testing.T) {
+	serviceFile := writeServiceFile(t, t.TempDir(), "services.conf", testServiceFile)
+	setPgServiceEnv(t, map[string]string{"PGSERVICEFILE": serviceFile, "PGSERVICE": "app"})
+
+	want := "dbname=app host=db.internal port=6432 user=reader"
+	if got := dsn(t, "postgres://"); got != want {
+		t.Errorf("postgresDSN() = %q, want %q", got, want)
+	}
+	for _, name := range []string{"PGSERVICE", "PGSERVICEFILE", "PGSYSCONFDIR"} {
+		if _, ok := os.LookupEnv(name); ok {
+			t.Errorf("%s is still set", name)
+		}
+	}
+
+	// lib/pq panics on these variables; it must now accept the environment.
+	if _, err := pq.NewConnector(want); err != nil {
+		t.Errorf("pq.NewConnector() error = %v", err)
+	}
+
+	// The next database of the run still uses the service.
+	if got := dsn(t, "postgres://"); got != want {
+		t.Errorf("second postgresDSN() = %q, want %q", got, want)
+	}
+}
+
+func TestLookupPgService(t *testing.T) {
+	setPgServiceEnv(t, nil)
+	home, sysconf := os.Getenv("HOME"), os.Getenv("PGSYSCONFDIR")
+	writeServiceFile(t, home, ".pg_service.conf", "[app]\nhost=home.internal\n")
+	writeServiceFile(t, sysconf, "pg_service.conf", "[app]\nhost=system.internal\n[shared]\nhost=shared.internal\n")
+	custom := writeServiceFile(t, t.TempDir(), "custom.conf", "[app]\nhost=custom.internal\n")
+
+	tests := []struct {
+		name    string
+		service string
+		env     map[string]string
+		want    string
+	}{
+		{"user file first", "app", map[string]string{"PGSYSCONFDIR": sysconf}, "home.internal"},
+		{"PGSERVICEFILE replaces the user file", "app", map[string]string{"PGSERVICEFILE": custom, "PGSYSCONFDIR": sysconf}, "custom.internal"},
+		{"system file", "shared", map[string]string{"PGSYSCONFDIR": sysconf}, "shared.internal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := lookupPgService(tt.service, tt.env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if settings["host"] != tt.want {
+				t.Errorf("host = %q, want %q", settings["host"], tt.want)
+			}
+		})
+	}
+
+	if _, err := lookupPgService("nosuch", map[string]string{"PGSYSCONFDIR": sysconf}); err == nil {
+		t.Error("lookupPgService() of an unknown service returned no error")
+	}
+}
+
+func TestReadPgService(t *testing.T) {
+	dir := t.TempDir()
+	path := writeServiceFile(t, dir, "services.conf", testServiceFile)
+
+	settings, found, err := readPgService(path, "app")
+	if err != nil || !found {
+		t.Fatalf("readPgService() = %v, %v, %v", settings, found, err)
+	}
+	want := map[string]string{"host": "db.internal", "port": "6432", "dbname": "app", "user": "reader"}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("settings = %v, want %v", settings, want)
+	}
+
+	if _, found, err := readPgService(path, "nosuch"); found || err != nil {
+		t.Errorf("readPgService() of an unknown service = %v, %v", found, err)
+	}
+	if _, found, err := readPgService(filepath.Join(dir, "missing.conf"), "app"); found || err != nil {
+		t.Errorf("readPgService() of a missing file = %v, %v", found, err)
+	}
+
+	broken := writeServiceFile(t, dir, "broken.conf", "[app]\nhost=db\nnot a setting\n")
+	if _, _, err := readPgService(broken, "app"); err == nil || !strings.Contains(err.Error(), "broken.conf:3: syntax error") {
+		t.Errorf("readPgService() of a broken file error = %v", err)
+	}
+}
@@ -2,55 +2,90 @@ package database
 
 import (
 	"database/sql"
-	"dbv/internal/schema"
 	"dbv/pkg/config"
+	"dbv/pkg/extractor"
+	"dbv/pkg/filter"
+	"dbv/pkg/schema"
 	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type PostgreSQLExtractor struct {
     db *sql.DB
 }
 
+func init() {
+    extractor.Register("postgres", func(db *sql.DB, _ string) (extractor.SchemaExtractor, error) {
+        return &PostgreSQLExtractor{db: db}, nil
+    })
+    extractor.RegisterScheme("postgres", "postgres", postgresDSN)
+    extractor.RegisterScheme("postgresql", "postgres", postgresDSN)
+}
+
 func (p *PostgreSQLExtractor) ExtractSchema(cfg config.SchemaConfig) (*schema.Schema, error) {
     s := &schema.Schema{
         Database:    "postgresql",
         GeneratedAt: time.Now(),
     }
 
-    tables, err := p.extractTables(cfg)
+    schemas := cfg.Schemas
+    if len(schemas) == 0 {
+        schemas = []string{"public"}
+    }
+
+    f, err := filter.NewTables(cfg)
+    if err != nil {
+        return nil, err
+    }
+
+    tables, err := p.extractTables(cfg, f, schemas)
     if err != nil {
         return nil, err
     }
     s.Tables = tables
 
     if cfg.IncludeViews {
-        views, err := p.extractViews(cfg)
+        views, err := p.extractViews(cfg, f, schemas)
         if err != nil {
             return nil, err
         }
         s.Views = views
     }
 
-    foreignKeys, err := p.extractForeignKeys(cfg)
+    foreignKeys, err := p.extractForeignKeys(f, schemas)
     if err != nil {
         return nil, err
     }
     s.ForeignKeys = foreignKeys
 
+    indexes, err := p.extractIndexes(f, schemas)
+    if err != nil {
+        return nil, err
+    }
+    s.Indexes = indexes
+
+    enums, err := p.extractEnums(schemas)
+    if err != nil {
+        return nil, err
+    }
+    s.Enums = enums
+
     return s, nil
 }
 
-func (p *PostgreSQLExtractor) extractTables(cfg config.SchemaConfig) ([]schema.Table, error) {
+func (p *PostgreSQLExtractor) extractTables(cfg config.SchemaConfig, f *filter.Tables, schemas []string) ([]schema.Table, error) {
     query := `
-        SELECT t.table_name, t.table_type, COALESCE(obj_description(c.oid), '') as comment
+        SELECT t.table_schema, t.table_name, t.table_type, COALESCE(obj_description(c.oid), '') as comment, c.reltuples
         FROM information_schema.tables t
-        LEFT JOIN pg_class c ON c.relname = t.table_name
-        WHERE t.table_schema = 'public' AND t.table_type = 'BASE TABLE'
-        ORDER BY t.table_name
+        LEFT JOIN pg_namespace n ON n.nspname = t.table_schema
+        LEFT JOIN pg_class c ON c.relname = t.table_name AND c.relnamespace = n.oid
+        WHERE t.table_schema = ANY($1) AND t.table_type = 'BASE TABLE'
+        ORDER BY t.table_schema, t.table_name
     `
 
-    rows, err := p.db.Query(query)
+    rows, err := p.db.Query(query, pq.Array(schemas))
     if err != nil {
         return nil, err
     }
@@ -59,42 +94,57 @@ func (p *PostgreSQLExtractor) extractTables(cfg config.SchemaConfig) ([]schema.T
     var tables []schema.Table
     for rows.Next() {
         var table schema.Table
-        if err := rows.Scan(&table.Name, &table.Type, &table.Comment); err != nil {
+        var reltuples sql.NullFloat64
+        if err := rows.Scan(&table.Schema, &table.Name, &table.Type, &table.Comment, &reltuples); err != nil {
             return nil, err
         }
 
-        if len(cfg.IncludeTables) > 0 && !contains(cfg.IncludeTables, table.Name) {
-            continue
+        // reltuples is -1 for tables that were never vacuumed or analyzed.
+        if reltuples.Valid && reltuples.Float64 >= 0 {
+            estimate := int64(reltuples.Float64)
+            table.RowEstimate = &estimate
         }
-        if contains(cfg.ExcludeTables, table.Name) {
+
+        if !f.Allow(table.Schema, table.Name) {
             continue
         }
 
-        table.Schema = "public"
-        
-        columns, err := p.extractColumns(table.Name)
+        tables = append(tables, table)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    err = forEach(len(tables), cfg.Parallelism, func(i int) error {
+        table := &tables[i]
+
+        columns, err := p.extractColumns(table.Schema, table.Name)
         if err != nil {
-            return nil, err
+            return err
         }
         table.Columns = columns
 
-        primaryKeys, err := p.extractPrimaryKeys(table.Name)
+        primaryKeys, err := p.extractPrimaryKeys(table.Schema, table.Name)
         if err != nil {
-            return nil, err
+            return err
         }
         table.PrimaryKeys = primaryKeys
 
-        tables = append(tables, table)
+        return nil
+    })
+    if err != nil {
+        return nil, err
     }
 
     return tables, nil
 }
 
-func (p *PostgreSQLExtractor) extractColumns(tableName string) ([]schema.Column, error) {
+func (p *PostgreSQLExtractor) extractColumns(schemaName, tableName string) ([]schema.Column, error) {
     query := `
         SELECT 
             c.column_name, 
             c.data_type,
+            c.udt_name,
             c.character_maximum_length,
             c.numeric_precision,
             c.numeric_scale,
@@ -102,12 +152,13 @@ func (p *PostgreSQLExtractor) extractColumns(tableName string) ([]schema.Column,
             c.column_default,
             COALESCE(col_description(pgc.oid, c.ordinal_position), '') as comment
         FROM information_schema.columns c
-        LEFT JOIN pg_class pgc ON pgc.relname = c.table_name
-        WHERE c.table_schema = 'public' AND c.table_name = $1
+        LEFT JOIN pg_namespace n ON n.nspname = c.table_schema
+        LEFT JOIN pg_class pgc ON pgc.relname = c.table_name AND pgc.relnamespace = n.oid
+        WHERE c.table_schema = $1 AND c.table_name = $2
         ORDER BY c.ordinal_position
     `
 
-    rows, err := p.db.Query(query, tableName)
+    rows, err := p.db.Query(query, schemaName, tableName)
     if err != nil {
         return nil, err
     }
@@ -118,10 +169,12 @@ func (p *PostgreSQLExtractor) extractColumns(tableName string) ([]schema.Column,
         var col schema.Column
         var length, precision, scale sql.NullInt64
         var defaultValue sql.NullString
+        var udtName string
 
         if err := rows.Scan(
             &col.Name,
             &col.Type,
+            &udtName,
             &length,
             &precision,
             &scale,
@@ -132,6 +185,14 @@ func (p *PostgreSQLExtractor) extractColumns(tableName string) ([]schema.Column,
             return nil, err
         }
 
+        // Enums and other user-defined types are named by their type, and
+        // arrays by their element type, which udt_name prefixes with _.
+        switch col.Type {
+        case "USER-DEFINED":
+            col.Type = udtName
+        case "ARRAY":
+            col.Type = strings.TrimPrefix(udtName, "_") + "[]"
+        }
         if length.Valid {
             l := int(length.Int64)
             col.Length = &l
@@ -151,22 +212,23 @@ func (p *PostgreSQLExtractor) extractColumns(tableName string) ([]schema.Column,
         columns = append(columns, col)
     }
 
-    return columns, nil
+    return columns, rows.Err()
 }
 
-func (p *PostgreSQLExtractor) extractPrimaryKeys(tableName string) ([]string, error) {
+func (p *PostgreSQLExtractor) extractPrimaryKeys(schemaName, tableName string) ([]string, error) {
     query := `
         SELECT kcu.column_name
         FROM information_schema.table_constraints tc
         JOIN information_schema.key_column_usage kcu 
             ON tc.constraint_name = kcu.constraint_name
-        WHERE tc.table_schema = 'public' 
-            AND tc.table_name = $1 
+            AND tc.constraint_schema = kcu.constraint_schema
+        WHERE tc.table_schema = $1 
+            AND tc.table_name = $2 
             AND tc.constraint_type = 'PRIMARY KEY'
         ORDER BY kcu.ordinal_position
     `
 
-    rows, err := p.db.Query(query, tableName)
+    rows, err := p.db.Query(query, schemaName, tableName)
     if err != nil {
         return nil, err
     }
@@ -181,18 +243,18 @@ func (p *PostgreSQLExtractor) extractPrimaryKeys(tableName string) ([]string, er
         primaryKeys = append(primaryKeys, columnName)
     }
 
-    return primaryKeys, nil
+    return primaryKeys, rows.Err()
 }
 
-func (p *PostgreSQLExtractor) extractViews(cfg config.SchemaConfig) ([]schema.View, error) {
+func (p *PostgreSQLExtractor) extractViews(cfg config.SchemaConfig, f *filter.Tables, schemas []string) ([]schema.View, error) {
     query := `
-        SELECT table_name, view_definition
+        SELECT table_schema, table_name, view_definition
         FROM information_schema.views
-        WHERE table_schema = 'public'
-        ORDER BY table_name
+        WHERE table_schema = ANY($1)
+        ORDER BY table_schema, table_name
     `
 
-    rows, err := p.db.Query(query)
+    rows, err := p.db.Query(query, pq.Array(schemas))
     if err != nil {
         return nil, err
     }
@@ -201,53 +263,77 @@ func (p *PostgreSQLExtractor) extractViews(cfg config.SchemaConfig) ([]schema.Vi
     var views []schema.View
     for rows.Next() {
         var view schema.View
-        if err := rows.Scan(&view.Name, &view.Definition); err != nil {
+        var definition sql.NullString
+        if err := rows.Scan(&view.Schema, &view.Name, &definition); err != nil {
             return nil, err
         }
+        view.Definition = definition.String
 
-        if len(cfg.IncludeTables) > 0 && !contains(cfg.IncludeTables, view.Name) {
-            continue
-        }
-        if contains(cfg.ExcludeTables, view.Name) {
+        if !f.Allow(view.Schema, view.Name) {
             continue
         }
 
-        view.Schema = "public"
-        
-        columns, err := p.extractColumns(view.Name)
+        views = append(views, view)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    err = forEach(len(views), cfg.Parallelism, func(i int) error {
+        columns, err := p.extractColumns(views[i].Schema, views[i].Name)
         if err != nil {
-            return nil, err
+            return err
         }
-        view.Columns = columns
-
-        views = append(views, view)
+        views[i].Columns = columns
+        return nil
+    })
+    if err != nil {
+        return nil, err
     }
 
     return views, nil
 }
 
-func (p *PostgreSQLExtractor) extractForeignKeys(cfg config.SchemaConfig) ([]schema.ForeignKey, error) {
+func (p *PostgreSQLExtractor) extractForeignKeys(f *filter.Tables, schemas []string) ([]schema.ForeignKey, error) {
+    // conkey and confkey list the columns of both sides in the same order,
+    // so unnesting them together pairs the columns of composite keys.
     query := `
         SELECT
-            tc.constraint_name,
-            tc.table_name,
-            kcu.column_name,
-            ccu.table_name AS foreign_table_name,
-            ccu.column_name AS foreign_column_name,
-            rc.update_rule,
-            rc.delete_rule
-        FROM information_schema.table_constraints AS tc
-        JOIN information_schema.key_column_usage AS kcu
-            ON tc.constraint_name = kcu.constraint_name
-        JOIN information_schema.constraint_column_usage AS ccu
-            ON ccu.constraint_name = tc.constraint_name
-        JOIN information_schema.referential_constraints AS rc
-            ON rc.constraint_name = tc.constraint_name
-        WHERE tc.constraint_type = 'FOREIGN KEY'
-            AND tc.table_schema = 'public'
+            c.conname,
+            n.nspname,
+            cl.relname,
+            a.attname,
+            fn.nspname AS foreign_table_schema,
+            fcl.relname AS foreign_table_name,
+            fa.attname AS foreign_column_name,
+            CASE c.confupdtype
+                WHEN 'c' THEN 'CASCADE'
+                WHEN 'n' THEN 'SET NULL'
+                WHEN 'd' THEN 'SET DEFAULT'
+                WHEN 'r' THEN 'RESTRICT'
+                ELSE 'NO ACTION'
+            END AS update_rule,
+            CASE c.confdeltype
+                WHEN 'c' THEN 'CASCADE'
+                WHEN 'n' THEN 'SET NULL'
+                WHEN 'd' THEN 'SET DEFAULT'
+                WHEN 'r' THEN 'RESTRICT'
+                ELSE 'NO ACTION'
+            END AS delete_rule
+        FROM pg_constraint c
+        JOIN pg_class cl ON cl.oid = c.conrelid
+        JOIN pg_namespace n ON n.oid = cl.relnamespace
+        JOIN pg_class fcl ON fcl.oid = c.confrelid
+        JOIN pg_namespace fn ON fn.oid = fcl.relnamespace
+        CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, foreign_attnum, position)
+        JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+        JOIN pg_attribute fa ON fa.attrelid = c.confrelid AND fa.attnum = k.foreign_attnum
+        WHERE c.contype = 'f'
+            AND n.nspname = ANY($1)
+        ORDER BY n.nspname, cl.relname, c.conname, k.position
     `
 
-    rows, err := p.db.Query(query)
+    rows, err := p.db.Query(query, pq.Array(schemas))
     if err != nil {
         return nil, err
     }
@@ -258,8 +344,10 @@ func (p *PostgreSQLExtractor) extractForeignKeys(cfg config.SchemaConfig) ([]sch
         var fk schema.ForeignKey
         if err := rows.Scan(
             &fk.Name,
+            &fk.Schema,
             &fk.Table,
             &fk.Column,
+            &fk.ReferencedSchema,
             &fk.ReferencedTable,
             &fk.ReferencedColumn,
             &fk.OnUpdate,
@@ -268,17 +356,103 @@ func (p *PostgreSQLExtractor) extractForeignKeys(cfg config.SchemaConfig) ([]sch
             return nil, err
         }
 
-        if len(cfg.IncludeTables) > 0 && !contains(cfg.IncludeTables, fk.Table) && !contains(cfg.IncludeTables, fk.ReferencedTable) {
+        if !f.AllowForeignKey(fk) {
             continue
         }
-        if contains(cfg.ExcludeTables, fk.Table) || contains(cfg.ExcludeTables, fk.ReferencedTable) {
+
+        foreignKeys = append(foreignKeys, fk)
+    }
+
+    return foreignKeys, rows.Err()
+}
+
+func (p *PostgreSQLExtractor) extractIndexes(f *filter.Tables, schemas []string) ([]schema.Index, error) {
+    query := `
+        SELECT
+            n.nspname,
+            t.relname,
+            i.relname,
+            ix.indisunique,
+            ix.indisprimary,
+            am.amname,
+            ARRAY(
+                SELECT pg_get_indexdef(ix.indexrelid, k, true)
+                FROM generate_series(1, ix.indnkeyatts) AS k
+                ORDER BY k
+            ) AS columns
+        FROM pg_index ix
+        JOIN pg_class i ON i.oid = ix.indexrelid
+        JOIN pg_class t ON t.oid = ix.indrelid
+        JOIN pg_namespace n ON n.oid = t.relnamespace
+        JOIN pg_am am ON am.oid = i.relam
+        WHERE n.nspname = ANY($1) AND t.relkind IN ('r', 'p')
+        ORDER BY n.nspname, t.relname, i.relname
+    `
+
+    rows, err := p.db.Query(query, pq.Array(schemas))
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var indexes []schema.Index
+    for rows.Next() {
+        var index schema.Index
+        if err := rows.Scan(
+            &index.Schema,
+            &index.Table,
+            &index.Name,
+            &index.IsUnique,
+            &index.IsPrimary,
+            &index.Type,
+            pq.Array(&index.Columns),
+        ); err != nil {
+            return nil, err
+        }
+
+        if !f.Allow(index.Schema, index.Table) {
             continue
         }
 
-        foreignKeys = append(foreignKeys, fk)
+        indexes = append(indexes, index)
+    }
+
+    return indexes, rows.Err()
+}
+
+func (p *PostgreSQLExtractor) extractEnums(schemas []string) ([]schema.Enum, error) {
+    query := `
+        SELECT n.nspname, t.typname, COALESCE(obj_description(t.oid, 'pg_type'), ''), e.enumlabel
+        FROM pg_type t
+        JOIN pg_namespace n ON n.oid = t.typnamespace
+        JOIN pg_enum e ON e.enumtypid = t.oid
+        WHERE n.nspname = ANY($1)
+        ORDER BY n.nspname, t.typname, e.enumsortorder
+    `
+
+    rows, err := p.db.Query(query, pq.Array(schemas))
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var enums []schema.Enum
+    for rows.Next() {
+        var enum schema.Enum
+        var value string
+        if err := rows.Scan(&enum.Schema, &enum.Name, &enum.Comment, &value); err != nil {
+            return nil, err
+        }
+
+        if n := len(enums); n > 0 && enums[n-1].Schema == enum.Schema && enums[n-1].Name == enum.Name {
+            enums[n-1].Values = append(enums[n-1].Values, value)
+            continue
+        }
+        enum.Values = []string{value}
+        enums = append(enums, enum)
     }
 
-    return foreignKeys, nil
+    return enums, rows.Err()
 }
 
 func contains(slice []string, item string) bool {
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"dbv/pkg/config"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestPostgreSQLCompositeForeignKey runs against the server named by
+// $DBV_TEST_POSTGRES_URL, in a schema of its own that is dropped afterwards.
+func TestPostgreSQLCompositeForeignKey(t *testing.T) {
+	databaseURL := os.Getenv("DBV_TEST_POSTGRES_URL")
+	if databaseURL == "" {
+		t.Skip("DBV_TEST_POSTGRES_URL is not set")
+	}
+
+	c, err := NewConnector(databaseURL, config.SSHConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	name := fmt.Sprintf("dbv_test_%d", time.Now().UnixNano())
+	statements := []string{
+		`CREATE SCHEMA ` + name,
+		`CREATE TABLE ` + name + `.orders (region text, id int, PRIMARY KEY (region, id))`,
+		`CREATE TABLE ` + name + `.lines (
+			id int PRIMARY KEY,
+			order_id int,
+			order_region text,
+			FOREIGN KEY (order_region, order_id) REFERENCES ` + name + `.orders (region, id) ON DELETE CASCADE
+		)`,
+	}
+	t.Cleanup(func() { c.db.Exec(`DROP SCHEMA ` + name + ` CASCADE`) })
+	for _, statement := range statements {
+		if _, err := c.db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := c.ExtractSchema(config.SchemaConfig{Schemas: []string{name}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pairs [][2]string
+	for _, fk := range s.ForeignKeys {
+		if fk.ReferencedSchema != name || fk.ReferencedTable != "orders" || fk.OnDelete != "CASCADE" {
+			t.Errorf("unexpected foreign key %+v", fk)
+		}
+		pairs = append(pairs, [2]string{fk.Column, fk.ReferencedColumn})
+	}
+	want := [][2]string{{"order_region", "region"}, {"order_id", "id"}}
+	if !reflect.DeepEqual(pairs, want) {
+		t.Errorf("column pairs = %v, want %v", pairs, want)
+	}
+}
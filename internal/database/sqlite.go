@@ -2,14 +2,123 @@ package database
 
 import (
 	"database/sql"
-	"dbv/internal/schema"
 	"dbv/pkg/config"
+	"dbv/pkg/extractor"
+	"dbv/pkg/filter"
+	"dbv/pkg/schema"
 	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 )
 
 type SQLiteExtractor struct {
-    db *sql.DB
+    db          *sql.DB
+    attachments []sqliteAttachment
+}
+
+// sqliteAttachment is an additional database file ATTACHed under name.
+type sqliteAttachment struct {
+    name string
+    path string
+}
+
+func init() {
+    extractor.Register("sqlite3", func(db *sql.DB, databaseURL string) (extractor.SchemaExtractor, error) {
+        attachments, err := parseSQLiteAttachments(databaseURL)
+        if err != nil {
+            return nil, err
+        }
+        return &SQLiteExtractor{db: db, attachments: attachments}, nil
+    })
+    extractor.RegisterScheme("sqlite", "sqlite3", sqliteDSN)
+    extractor.RegisterScheme("sqlite3", "sqlite3", sqliteDSN)
+}
+
+// sqliteDSN strips the attach parameters, which are handled by the
+// extractor, and passes every other parameter on to the driver.
+func sqliteDSN(u *url.URL, databaseURL string) (string, error) {
+    dsn, err := extractor.TrimScheme(u, databaseURL)
+    if err != nil {
+        return "", err
+    }
+
+    path, rawQuery, ok := strings.Cut(dsn, "?")
+    if !ok {
+        return dsn, nil
+    }
+
+    params, err := url.ParseQuery(rawQuery)
+    if err != nil {
+        return "", err
+    }
+    params.Del("attach")
+    if len(params) == 0 {
+        return path, nil
+    }
+
+    return path + "?" + params.Encode(), nil
+}
+
+// parseSQLiteAttachments reads the attach parameters of a SQLite URL. Each is
+// either "name=path" or a bare path or glob, in which case the file name
+// without its extension becomes the schema name:
+//
+//	sqlite://./app.db?attach=audit=./audit.db&attach=./shards/*.db
+func parseSQLiteAttachments(databaseURL string) ([]sqliteAttachment, error) {
+    u, err := url.Parse(databaseURL)
+    if err != nil {
+        return nil, err
+    }
+
+    used := map[string]bool{"main": true, "temp": true}
+    var attachments []sqliteAttachment
+    add := func(name, path string) {
+        name = sqliteSchemaName(name)
+        for base, n := name, 2; used[name]; n++ {
+            name = fmt.Sprintf("%s_%d", base, n)
+        }
+        used[name] = true
+        attachments = append(attachments, sqliteAttachment{name: name, path: path})
+    }
+
+    for _, value := range u.Query()["attach"] {
+        if name, path, ok := strings.Cut(value, "="); ok {
+            add(name, path)
+            continue
+        }
+
+        matches, err := filepath.Glob(value)
+        if err != nil {
+            return nil, fmt.Errorf("invalid attach pattern %q: %w", value, err)
+        }
+        if len(matches) == 0 {
+            return nil, fmt.Errorf("attach pattern %q matches no files", value)
+        }
+        sort.Strings(matches)
+
+        for _, path := range matches {
+            add(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), path)
+        }
+    }
+
+    return attachments, nil
+}
+
+func sqliteSchemaName(name string) string {
+    name = strings.Map(func(r rune) rune {
+        switch {
+        case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+            return r
+        }
+        return '_'
+    }, name)
+    if name == "" {
+        return "db"
+    }
+    return name
 }
 
 func (s *SQLiteExtractor) ExtractSchema(cfg config.SchemaConfig) (*schema.Schema, error) {
@@ -18,36 +127,119 @@ func (s *SQLiteExtractor) ExtractSchema(cfg config.SchemaConfig) (*schema.Schema
         GeneratedAt: time.Now(),
     }
 
-    tables, err := s.extractTables(cfg)
+    f, err := filter.NewTables(cfg)
     if err != nil {
         return nil, err
     }
-    sch.Tables = tables
 
-    if cfg.IncludeViews {
-        views, err := s.extractViews(cfg)
+    if err := s.attach(); err != nil {
+        return nil, err
+    }
+
+    for _, schemaName := range s.schemaNames(cfg) {
+        tables, err := s.extractTables(f, schemaName)
         if err != nil {
             return nil, err
         }
-        sch.Views = views
-    }
+        sch.Tables = append(sch.Tables, tables...)
 
-    foreignKeys, err := s.extractForeignKeys(cfg)
-    if err != nil {
-        return nil, err
+        if cfg.IncludeViews {
+            views, err := s.extractViews(f, schemaName)
+            if err != nil {
+                return nil, err
+            }
+            sch.Views = append(sch.Views, views...)
+        }
+
+        foreignKeys, err := s.extractForeignKeys(f, tables)
+        if err != nil {
+            return nil, err
+        }
+        sch.ForeignKeys = append(sch.ForeignKeys, foreignKeys...)
+
+        indexes, err := s.extractIndexes(tables)
+        if err != nil {
+            return nil, err
+        }
+        sch.Indexes = append(sch.Indexes, indexes...)
     }
-    sch.ForeignKeys = foreignKeys
+
+    linkAcrossDatabases(sch)
 
     return sch, nil
 }
 
-func (s *SQLiteExtractor) extractTables(cfg config.SchemaConfig) ([]schema.Table, error) {
-    query := `
+// attach ATTACHes the additional database files. ATTACH only applies to the
+// connection it runs on, so the pool is pinned to a single connection.
+func (s *SQLiteExtractor) attach() error {
+    if len(s.attachments) == 0 {
+        return nil
+    }
+
+    s.db.SetMaxOpenConns(1)
+    s.db.SetMaxIdleConns(1)
+
+    for _, a := range s.attachments {
+        if _, err := s.db.Exec("ATTACH DATABASE ? AS "+quoteIdent(a.name), a.path); err != nil {
+            return fmt.Errorf("failed to attach %s as %s: %w", a.path, a.name, err)
+        }
+    }
+
+    return nil
+}
+
+// schemaNames returns "main" followed by the attached databases, restricted
+// to cfg.Schemas when it is set.
+func (s *SQLiteExtractor) schemaNames(cfg config.SchemaConfig) []string {
+    names := []string{"main"}
+    for _, a := range s.attachments {
+        names = append(names, a.name)
+    }
+
+    if len(cfg.Schemas) == 0 {
+        return names
+    }
+
+    var selected []string
+    for _, name := range names {
+        if contains(cfg.Schemas, name) {
+            selected = append(selected, name)
+        }
+    }
+    return selected
+}
+
+// linkAcrossDatabases points foreign keys whose referenced table does not
+// exist in their own database at the extracted table of that name in another
+// database. SQLite cannot enforce such references, but applications that
+// split data across attached files rely on them as logical links.
+func linkAcrossDatabases(sch *schema.Schema) {
+    schemasByTable := make(map[string][]string)
+    for _, table := range sch.Tables {
+        schemasByTable[strings.ToLower(table.Name)] = append(schemasByTable[strings.ToLower(table.Name)], table.Schema)
+    }
+
+    for i := range sch.ForeignKeys {
+        fk := &sch.ForeignKeys[i]
+        candidates := schemasByTable[strings.ToLower(fk.ReferencedTable)]
+        if len(candidates) == 0 || contains(candidates, fk.ReferencedSchema) {
+            continue
+        }
+
+        fk.ReferencedSchema = candidates[0]
+        if contains(candidates, "main") {
+            fk.ReferencedSchema = "main"
+        }
+    }
+}
+
+func (s *SQLiteExtractor) extractTables(f *filter.Tables, schemaName string) ([]schema.Table, error) {
+    query := fmt.Sprintf(`
         SELECT name, type, sql
-        FROM sqlite_master
-        WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+        FROM %s.sqlite_master
+        WHERE type = 'table' AND name NOT LIKE 'sqlite_%%'
         ORDER BY name
-    `
+    `, quoteIdent(schemaName))
 
     rows, err := s.db.Query(query)
     if err != nil {
@@ -63,36 +255,80 @@ func (s *SQLiteExtractor) extractTables(cfg config.SchemaConfig) ([]schema.Table
             return nil, err
         }
 
-        if len(cfg.IncludeTables) > 0 && !contains(cfg.IncludeTables, table.Name) {
-            continue
-        }
-        if contains(cfg.ExcludeTables, table.Name) {
+        if !f.Allow(schemaName, table.Name) {
             continue
         }
 
-        table.Schema = "main"
+        table.Schema = schemaName
         table.Type = "BASE TABLE"
+        tables = append(tables, table)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+    rows.Close()
+
+    if err := s.extractRowEstimates(schemaName, tables); err != nil {
+        return nil, err
+    }
 
-        columns, err := s.extractColumns(table.Name)
+    for i := range tables {
+        columns, err := s.extractColumns(schemaName, tables[i].Name)
         if err != nil {
             return nil, err
         }
-        table.Columns = columns
+        tables[i].Columns = columns
 
-        primaryKeys, err := s.extractPrimaryKeys(table.Name)
+        primaryKeys, err := s.extractPrimaryKeys(schemaName, tables[i].Name)
         if err != nil {
             return nil, err
         }
-        table.PrimaryKeys = primaryKeys
-
-        tables = append(tables, table)
+        tables[i].PrimaryKeys = primaryKeys
     }
 
     return tables, nil
 }
 
-func (s *SQLiteExtractor) extractColumns(tableName string) ([]schema.Column, error) {
-    query := fmt.Sprintf("PRAGMA table_info(%s)", tableName)
+// extractRowEstimates reads the row counts that ANALYZE records in
+// sqlite_stat1. The stat column starts with the number of rows, which the
+// cast extracts. Databases that were never analyzed have no estimates.
+func (s *SQLiteExtractor) extractRowEstimates(schemaName string, tables []schema.Table) error {
+    var exists int
+    query := fmt.Sprintf("SELECT COUNT(*) FROM %s.sqlite_master WHERE type = 'table' AND name = 'sqlite_stat1'", quoteIdent(schemaName))
+    if err := s.db.QueryRow(query).Scan(&exists); err != nil || exists == 0 {
+        return err
+    }
+
+    query = fmt.Sprintf("SELECT tbl, MAX(CAST(stat AS INTEGER)) FROM %s.sqlite_stat1 GROUP BY tbl", quoteIdent(schemaName))
+    rows, err := s.db.Query(query)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    estimates := make(map[string]int64)
+    for rows.Next() {
+        var name string
+        var estimate int64
+        if err := rows.Scan(&name, &estimate); err != nil {
+            return err
+        }
+        estimates[name] = estimate
+    }
+    if err := rows.Err(); err != nil {
+        return err
+    }
+
+    for i := range tables {
+        if estimate, ok := estimates[tables[i].Name]; ok {
+            tables[i].RowEstimate = &estimate
+        }
+    }
+    return nil
+}
+
+func (s *SQLiteExtractor) extractColumns(schemaName, tableName string) ([]schema.Column, error) {
+    query := fmt.Sprintf("PRAGMA %s.table_info(%s)", quoteIdent(schemaName), quoteIdent(tableName))
 
     rows, err := s.db.Query(query)
     if err != nil {
@@ -120,7 +356,7 @@ func (s *SQLiteExtractor) extractColumns(tableName string) ([]schema.Column, err
         }
 
         col.IsNullable = notNull == 0
-        col.IsPrimaryKey = pk == 1
+        col.IsPrimaryKey = pk > 0
         if defaultValue.Valid {
             col.DefaultValue = &defaultValue.String
         }
@@ -128,11 +364,11 @@ func (s *SQLiteExtractor) extractColumns(tableName string) ([]schema.Column, err
         columns = append(columns, col)
     }
 
-    return columns, nil
+    return columns, rows.Err()
 }
 
-func (s *SQLiteExtractor) extractPrimaryKeys(tableName string) ([]string, error) {
-    query := fmt.Sprintf("PRAGMA table_info(%s)", tableName)
+func (s *SQLiteExtractor) extractPrimaryKeys(schemaName, tableName string) ([]string, error) {
+    query := fmt.Sprintf("PRAGMA %s.table_info(%s)", quoteIdent(schemaName), quoteIdent(tableName))
 
     rows, err := s.db.Query(query)
     if err != nil {
@@ -140,7 +376,8 @@ func (s *SQLiteExtractor) extractPrimaryKeys(tableName string) ([]string, error)
     }
     defer rows.Close()
 
-    var primaryKeys []string
+    // pk is the column's position in the primary key, or 0.
+    positions := make(map[int]string)
     for rows.Next() {
         var cid int
         var name, dataType string
@@ -152,21 +389,26 @@ func (s *SQLiteExtractor) extractPrimaryKeys(tableName string) ([]string, error)
             return nil, err
         }
 
-        if pk == 1 {
-            primaryKeys = append(primaryKeys, name)
+        if pk > 0 {
+            positions[pk] = name
         }
     }
 
-    return primaryKeys, nil
+    var primaryKeys []string
+    for i := 1; i <= len(positions); i++ {
+        primaryKeys = append(primaryKeys, positions[i])
+    }
+
+    return primaryKeys, rows.Err()
 }
 
-func (s *SQLiteExtractor) extractViews(cfg config.SchemaConfig) ([]schema.View, error) {
-    query := `
+func (s *SQLiteExtractor) extractViews(f *filter.Tables, schemaName string) ([]schema.View, error) {
+    query := fmt.Sprintf(`
         SELECT name, sql
-        FROM sqlite_master
+        FROM %s.sqlite_master
         WHERE type = 'view'
         ORDER BY name
-    `
+    `, quoteIdent(schemaName))
 
     rows, err := s.db.Query(query)
     if err != nil {
@@ -181,45 +423,47 @@ func (s *SQLiteExtractor) extractViews(cfg config.SchemaConfig) ([]schema.View,
             return nil, err
         }
 
-        if len(cfg.IncludeTables) > 0 && !contains(cfg.IncludeTables, view.Name) {
-            continue
-        }
-        if contains(cfg.ExcludeTables, view.Name) {
+        if !f.Allow(schemaName, view.Name) {
             continue
         }
 
-        view.Schema = "main"
+        view.Schema = schemaName
+        views = append(views, view)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+    rows.Close()
 
-        columns, err := s.extractColumns(view.Name)
+    for i := range views {
+        columns, err := s.extractColumns(schemaName, views[i].Name)
         if err != nil {
             return nil, err
         }
-        view.Columns = columns
-
-        views = append(views, view)
+        views[i].Columns = columns
     }
 
     return views, nil
 }
 
-func (s *SQLiteExtractor) extractForeignKeys(cfg config.SchemaConfig) ([]schema.ForeignKey, error) {
-    tables, err := s.extractTables(cfg)
-    if err != nil {
-        return nil, err
-    }
-
+func (s *SQLiteExtractor) extractForeignKeys(f *filter.Tables, tables []schema.Table) ([]schema.ForeignKey, error) {
     var foreignKeys []schema.ForeignKey
     for _, table := range tables {
-        query := fmt.Sprintf("PRAGMA foreign_key_list(%s)", table.Name)
+        query := fmt.Sprintf("PRAGMA %s.foreign_key_list(%s)", quoteIdent(table.Schema), quoteIdent(table.Name))
 
         rows, err := s.db.Query(query)
         if err != nil {
             return nil, err
         }
 
+        // The columns of a composite key share its id and are named after
+        // the first one, with a number added when that name is taken.
+        names := make(map[int]string)
+        used := make(map[string]bool)
         for rows.Next() {
             var fk schema.ForeignKey
             var id, seq int
+            var referencedColumn sql.NullString
             var onUpdate, onDelete, match string
 
             if err := rows.Scan(
@@ -227,7 +471,7 @@ func (s *SQLiteExtractor) extractForeignKeys(cfg config.SchemaConfig) ([]schema.
                 &seq,
                 &fk.ReferencedTable,
                 &fk.Column,
-                &fk.ReferencedColumn,
+                &referencedColumn,
                 &onUpdate,
                 &onDelete,
                 &match,
@@ -236,22 +480,111 @@ func (s *SQLiteExtractor) extractForeignKeys(cfg config.SchemaConfig) ([]schema.
                 return nil, err
             }
 
-            fk.Name = fmt.Sprintf("fk_%s_%s", table.Name, fk.Column)
+            if _, ok := names[id]; !ok {
+                name := fmt.Sprintf("fk_%s_%s", table.Name, fk.Column)
+                for n := 2; used[name]; n++ {
+                    name = fmt.Sprintf("fk_%s_%s_%d", table.Name, fk.Column, n)
+                }
+                names[id], used[name] = name, true
+            }
+            fk.Name = names[id]
+            fk.Ordinal = id + 1
+            fk.Schema = table.Schema
             fk.Table = table.Name
+            fk.ReferencedSchema = table.Schema
+            fk.ReferencedColumn = referencedColumn.String
             fk.OnUpdate = onUpdate
             fk.OnDelete = onDelete
 
-            if len(cfg.IncludeTables) > 0 && !contains(cfg.IncludeTables, fk.Table) && !contains(cfg.IncludeTables, fk.ReferencedTable) {
-                continue
-            }
-            if contains(cfg.ExcludeTables, fk.Table) || contains(cfg.ExcludeTables, fk.ReferencedTable) {
+            if !f.AllowForeignKey(fk) {
                 continue
             }
 
             foreignKeys = append(foreignKeys, fk)
         }
+        err = rows.Err()
         rows.Close()
+        if err != nil {
+            return nil, err
+        }
     }
 
     return foreignKeys, nil
+}
+
+func (s *SQLiteExtractor) extractIndexes(tables []schema.Table) ([]schema.Index, error) {
+    var indexes []schema.Index
+    for _, table := range tables {
+        query := fmt.Sprintf("PRAGMA %s.index_list(%s)", quoteIdent(table.Schema), quoteIdent(table.Name))
+
+        rows, err := s.db.Query(query)
+        if err != nil {
+            return nil, err
+        }
+
+        var tableIndexes []schema.Index
+        for rows.Next() {
+            var seq, unique, partial int
+            var index schema.Index
+            var origin string
+
+            if err := rows.Scan(&seq, &index.Name, &unique, &origin, &partial); err != nil {
+                rows.Close()
+                return nil, err
+            }
+
+            index.Schema = table.Schema
+            index.Table = table.Name
+            index.IsUnique = unique == 1
+            index.IsPrimary = origin == "pk"
+            index.Type = "btree"
+            tableIndexes = append(tableIndexes, index)
+        }
+        err = rows.Err()
+        rows.Close()
+        if err != nil {
+            return nil, err
+        }
+
+        for i := range tableIndexes {
+            columns, err := s.extractIndexColumns(table.Schema, tableIndexes[i].Name)
+            if err != nil {
+                return nil, err
+            }
+            tableIndexes[i].Columns = columns
+        }
+        indexes = append(indexes, tableIndexes...)
+    }
+
+    return indexes, nil
+}
+
+func (s *SQLiteExtractor) extractIndexColumns(schemaName, indexName string) ([]string, error) {
+    query := fmt.Sprintf("PRAGMA %s.index_info(%s)", quoteIdent(schemaName), quoteIdent(indexName))
+
+    rows, err := s.db.Query(query)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var columns []string
+    for rows.Next() {
+        var seqno, cid int
+        var name sql.NullString
+        if err := rows.Scan(&seqno, &cid, &name); err != nil {
+            return nil, err
+        }
+        if !name.Valid {
+            // An indexed expression; index_info does not give its text.
+            name.String = "(expression)"
+        }
+        columns = append(columns, name.String)
+    }
+
+    return columns, rows.Err()
+}
+
+func quoteIdent(name string) string {
+    return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
 }
\ No newline at end of file
@@ -0,0 +1,302 @@
+package database
+
+import (
+	"dbv/pkg/config"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const sshDialTimeout = 15 * time.Second
+
+// defaultPorts are the database ports assumed for tunneled URLs without one.
+var defaultPorts = map[string]string{
+	"postgres":   "5432",
+	"postgresql": "5432",
+	"mysql":      "3306",
+}
+
+// sshTunnel forwards connections accepted on a local listener to a database
+// host, through an SSH connection to a bastion.
+type sshTunnel struct {
+	listener net.Listener
+	remote   string
+	clients  []*ssh.Client
+	agent    net.Conn
+	wg       sync.WaitGroup
+}
+
+// openTunnel starts a tunnel for databaseURL and returns the URL rewritten to
+// connect through it. The database host in the URL is resolved on the far
+// side of the tunnel, so it may be a name only the bastion knows.
+func openTunnel(databaseURL string, cfg config.SSHConfig) (string, *sshTunnel, error) {
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defaultPort, ok := defaultPorts[strings.ToLower(u.Scheme)]
+	if !ok {
+		return "", nil, fmt.Errorf("ssh tunnel is not supported for %s URLs", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return "", nil, fmt.Errorf("ssh tunnel requires the database host in the URL")
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+	}
+
+	t := &sshTunnel{remote: net.JoinHostPort(u.Hostname(), port)}
+	if err := t.dial(cfg); err != nil {
+		t.Close()
+		return "", nil, err
+	}
+
+	t.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Close()
+		return "", nil, fmt.Errorf("failed to listen for ssh tunnel: %w", err)
+	}
+
+	t.wg.Add(1)
+	go t.serve()
+
+	u.Host = t.listener.Addr().String()
+	return u.String(), t, nil
+}
+
+// dial connects to the bastion, through the jump host when one is set.
+func (t *sshTunnel) dial(cfg config.SSHConfig) error {
+	if cfg.Host == "" {
+		return fmt.Errorf("ssh host cannot be empty")
+	}
+
+	auth, err := t.authMethods(cfg)
+	if err != nil {
+		return err
+	}
+	hostKeys, err := hostKeyCallback(cfg)
+	if err != nil {
+		return err
+	}
+
+	hostUser, hostAddr, err := sshAddress(cfg.Host, cfg.User)
+	if err != nil {
+		return err
+	}
+	hostConfig := &ssh.ClientConfig{
+		User:            hostUser,
+		Auth:            auth,
+		HostKeyCallback: hostKeys,
+		Timeout:         sshDialTimeout,
+	}
+
+	if cfg.Jump == "" {
+		client, err := ssh.Dial("tcp", hostAddr, hostConfig)
+		if err != nil {
+			return fmt.Errorf("failed to connect to ssh host %s: %w", hostAddr, err)
+		}
+		t.clients = append(t.clients, client)
+		return nil
+	}
+
+	jumpUser, jumpAddr, err := sshAddress(cfg.Jump, cfg.User)
+	if err != nil {
+		return err
+	}
+	jump, err := ssh.Dial("tcp", jumpAddr, &ssh.ClientConfig{
+		User:            jumpUser,
+		Auth:            auth,
+		HostKeyCallback: hostKeys,
+		Timeout:         sshDialTimeout,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to ssh jump host %s: %w", jumpAddr, err)
+	}
+	t.clients = append(t.clients, jump)
+
+	conn, err := jump.Dial("tcp", hostAddr)
+	if err != nil {
+		return fmt.Errorf("failed to reach ssh host %s from %s: %w", hostAddr, jumpAddr, err)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, hostAddr, hostConfig)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to connect to ssh host %s: %w", hostAddr, err)
+	}
+	t.clients = append(t.clients, ssh.NewClient(c, chans, reqs))
+
+	return nil
+}
+
+// authMethods offers the configured key file, then the keys of a running
+// ssh-agent. Without either, the default ~/.ssh/id_* keys are tried.
+func (t *sshTunnel) authMethods(cfg config.SSHConfig) ([]ssh.AuthMethod, error) {
+	var signers []ssh.Signer
+
+	if cfg.KeyFile != "" {
+		signer, err := loadKey(expandHome(cfg.KeyFile))
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	}
+
+	methods := []ssh.AuthMethod{}
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			t.agent = conn
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+
+	if len(signers) == 0 && len(methods) == 0 {
+		for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			signer, err := loadKey(expandHome(filepath.Join("~", ".ssh", name)))
+			if err == nil {
+				signers = append(signers, signer)
+			}
+		}
+	}
+
+	if len(signers) > 0 {
+		methods = append([]ssh.AuthMethod{ssh.PublicKeys(signers...)}, methods...)
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("no ssh key found: set a key file or start ssh-agent")
+	}
+
+	return methods, nil
+}
+
+func loadKey(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ssh key: %w", err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, fmt.Errorf("ssh key %s is passphrase protected, add it to ssh-agent instead", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ssh key %s: %w", path, err)
+	}
+
+	return signer, nil
+}
+
+// hostKeyCallback verifies host keys against the configured known_hosts file,
+// ~/.ssh/known_hosts by default. Unknown hosts are rejected.
+func hostKeyCallback(cfg config.SSHConfig) (ssh.HostKeyCallback, error) {
+	path := cfg.KnownHosts
+	if path == "" {
+		path = filepath.Join("~", ".ssh", "known_hosts")
+	}
+
+	callback, err := knownhosts.New(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known hosts: %w", err)
+	}
+	return callback, nil
+}
+
+// sshAddress splits [user@]host[:port] into a user and a host:port address,
+// defaulting to defaultUser, then the current user, and port 22.
+func sshAddress(spec, defaultUser string) (string, string, error) {
+	name := defaultUser
+	if at := strings.LastIndex(spec, "@"); at >= 0 {
+		name, spec = spec[:at], spec[at+1:]
+	}
+	if name == "" {
+		current, err := user.Current()
+		if err != nil {
+			return "", "", fmt.Errorf("ssh user not set: %w", err)
+		}
+		name = current.Username
+	}
+
+	if _, _, err := net.SplitHostPort(spec); err != nil {
+		spec = net.JoinHostPort(strings.Trim(spec, "[]"), "22")
+	}
+
+	return name, spec, nil
+}
+
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~"+string(filepath.Separator))
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
+
+// serve accepts local connections until the listener is closed.
+func (t *sshTunnel) serve() {
+	defer t.wg.Done()
+
+	for {
+		local, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+		go t.forward(local)
+	}
+}
+
+// forward copies data between a local connection and the remote database
+// until either side closes.
+func (t *sshTunnel) forward(local net.Conn) {
+	defer local.Close()
+
+	remote, err := t.clients[len(t.clients)-1].Dial("tcp", t.remote)
+	if err != nil {
+		return
+	}
+	defer remote.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(remote, local)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(local, remote)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// Close stops the listener and closes the SSH connections, innermost first.
+func (t *sshTunnel) Close() error {
+	var errs []error
+	if t.listener != nil {
+		errs = append(errs, t.listener.Close())
+		t.wg.Wait()
+	}
+	for i := len(t.clients) - 1; i >= 0; i-- {
+		errs = append(errs, t.clients[i].Close())
+	}
+	if t.agent != nil {
+		errs = append(errs, t.agent.Close())
+	}
+	return errors.Join(errs...)
+}
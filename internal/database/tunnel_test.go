@@ -0,0 +1,340 @@
+package database
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"dbv/pkg/config"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testSSHServer is an in-process SSH server that accepts one client key and
+// forwards direct-tcpip channels, as sshd does for ssh -L.
+type testSSHServer struct {
+	addr    string
+	hostKey ssh.Signer
+	// conns is the number of open client connections.
+	conns atomic.Int32
+	// forwards receives the destination of every forwarded channel.
+	forwards chan string
+}
+
+func startSSHServer(t *testing.T, clientKey ssh.PublicKey) *testSSHServer {
+	t.Helper()
+
+	s := &testSSHServer{hostKey: newSigner(t), forwards: make(chan string, 16)}
+	cfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, fmt.Errorf("unknown key")
+			}
+			return nil, nil
+		},
+	}
+	cfg.AddHostKey(s.hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	s.addr = listener.Addr().String()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn, cfg)
+		}
+	}()
+	return s
+}
+
+func (s *testSSHServer) handle(conn net.Conn, cfg *ssh.ServerConfig) {
+	defer conn.Close()
+	_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		return
+	}
+	s.conns.Add(1)
+	defer s.conns.Add(-1)
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "only direct-tcpip")
+			continue
+		}
+		var payload struct {
+			Host     string
+			Port     uint32
+			OrigHost string
+			OrigPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		target := net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port)))
+		s.forwards <- target
+
+		remote, err := net.Dial("tcp", target)
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			remote.Close()
+			continue
+		}
+		go ssh.DiscardRequests(requests)
+		// Either side closing closes both, so that a client going away is
+		// seen by the next hop.
+		go func() {
+			io.Copy(remote, channel)
+			remote.Close()
+			channel.Close()
+		}()
+		go func() {
+			io.Copy(channel, remote)
+			remote.Close()
+			channel.Close()
+		}()
+	}
+}
+
+// waitConns waits for the number of open connections to reach want.
+func (s *testSSHServer) waitConns(t *testing.T, want int32) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for s.conns.Load() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("ssh server %s has %d connections, want %d", s.addr, s.conns.Load(), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// startEchoServer stands in for the database: it echoes what it receives.
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// tunnelConfig writes the client key and a known_hosts file trusting the
+// given servers, and returns the config of a tunnel through them.
+func tunnelConfig(t *testing.T, clientKey ed25519.PrivateKey, trusted ...*testSSHServer) config.SSHConfig {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines string
+	for _, s := range trusted {
+		lines += knownhosts.Line([]string{knownhosts.Normalize(s.addr)}, s.hostKey.PublicKey()) + "\n"
+	}
+	knownHosts := filepath.Join(dir, "known_hosts")
+	if err := os.WriteFile(knownHosts, []byte(lines), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return config.SSHConfig{User: "dbv", KeyFile: keyFile, KnownHosts: knownHosts}
+}
+
+func newClientKey(t *testing.T) (ed25519.PrivateKey, ssh.PublicKey) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return private, key
+}
+
+// roundTrip sends a message through the tunneled URL and checks the echo.
+func roundTrip(t *testing.T, tunneled string) {
+	t.Helper()
+	u, err := url.Parse(tunneled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.DialTimeout("tcp", u.Host, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatal(err)
+	}
+	if string(reply) != "ping" {
+		t.Errorf("reply = %q, want %q", reply, "ping")
+	}
+}
+
+func expectForward(t *testing.T, s *testSSHServer, want string) {
+	t.Helper()
+	select {
+	case got := <-s.forwards:
+		if got != want {
+			t.Errorf("%s forwarded to %s, want %s", s.addr, got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("%s forwarded nothing, want %s", s.addr, want)
+	}
+}
+
+func TestTunnelDirect(t *testing.T) {
+	private, public := newClientKey(t)
+	bastion := startSSHServer(t, public)
+	database := startEchoServer(t)
+
+	cfg := tunnelConfig(t, private, bastion)
+	cfg.Host = bastion.addr
+
+	tunneled, tunnel, err := openTunnel("postgres://app:secret@"+database+"/app?sslmode=disable", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(tunneled)
+	if host, _, _ := net.SplitHostPort(u.Host); host != "127.0.0.1" || u.Path != "/app" || u.User.String() != "app:secret" {
+		t.Errorf("tunneled URL = %s, want the same URL on a local port", tunneled)
+	}
+
+	roundTrip(t, tunneled)
+	expectForward(t, bastion, database)
+	bastion.waitConns(t, 1)
+
+	if err := tunnel.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	bastion.waitConns(t, 0)
+	if conn, err := net.DialTimeout("tcp", u.Host, time.Second); err == nil {
+		conn.Close()
+		t.Error("the local listener still accepts connections after Close")
+	}
+}
+
+func TestTunnelJumpHost(t *testing.T) {
+	private, public := newClientKey(t)
+	jump := startSSHServer(t, public)
+	bastion := startSSHServer(t, public)
+	database := startEchoServer(t)
+
+	cfg := tunnelConfig(t, private, jump, bastion)
+	cfg.Host = bastion.addr
+	cfg.Jump = "dbv@" + jump.addr
+
+	tunneled, tunnel, err := openTunnel("postgres://app@"+database+"/app", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roundTrip(t, tunneled)
+	expectForward(t, jump, bastion.addr)
+	expectForward(t, bastion, database)
+	jump.waitConns(t, 1)
+	bastion.waitConns(t, 1)
+
+	if err := tunnel.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	bastion.waitConns(t, 0)
+	jump.waitConns(t, 0)
+	u, _ := url.Parse(tunneled)
+	if conn, err := net.DialTimeout("tcp", u.Host, time.Second); err == nil {
+		conn.Close()
+		t.Error("the local listener still accepts connections after Close")
+	}
+}
+
+func TestTunnelRejectsUnknownHostKey(t *testing.T) {
+	private, public := newClientKey(t)
+	bastion := startSSHServer(t, public)
+	impostor := &testSSHServer{addr: bastion.addr, hostKey: newSigner(t)}
+	database := startEchoServer(t)
+
+	for name, trusted := range map[string][]*testSSHServer{
+		"unknown host":   nil,
+		"mismatched key": {impostor},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := tunnelConfig(t, private, trusted...)
+			cfg.Host = bastion.addr
+
+			if _, tunnel, err := openTunnel("postgres://app@"+database+"/app", cfg); err == nil {
+				tunnel.Close()
+				t.Fatal("openTunnel() succeeded with an untrusted host key")
+			}
+			bastion.waitConns(t, 0)
+		})
+	}
+}
+
+func TestTunnelUnsupportedScheme(t *testing.T) {
+	if _, _, err := openTunnel("sqlite:///tmp/app.db", config.SSHConfig{Host: "bastion"}); err == nil {
+		t.Error("openTunnel() accepted a sqlite URL")
+	}
+}
@@ -0,0 +1,284 @@
+package generators
+
+import (
+	"dbv/internal/layout"
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+func init() {
+	generator.Register("d2", newD2)
+}
+
+// d2Generator renders a D2 diagram: a sql_table shape per table and view,
+// connections between the related columns and, when the schema spans
+// several schemas, a container per schema.
+type d2Generator struct {
+	direction  string
+	containers bool
+}
+
+func newD2(opts generator.Options) (generator.Generator, error) {
+	direction := strings.ToLower(opts.String("direction", "right"))
+	switch direction {
+	case "up", "down", "left", "right":
+	default:
+		return nil, fmt.Errorf("option direction: invalid value '%s'. Valid values: up, down, left, right", direction)
+	}
+	containers, err := opts.Bool("containers", true)
+	if err != nil {
+		return nil, err
+	}
+	return &d2Generator{direction: direction, containers: containers}, nil
+}
+
+func (g *d2Generator) Name() string        { return "d2" }
+func (g *d2Generator) Description() string { return "D2" }
+func (g *d2Generator) Extension() string   { return ".d2" }
+
+func (g *d2Generator) Options() []generator.Option {
+	return []generator.Option{
+		{Name: "direction", Description: "Layout direction: up, down, left or right", Default: "right"},
+		{Name: "containers", Description: "Group tables in a container per schema when there are several", Default: "true"},
+	}
+}
+
+func (g *d2Generator) Generate(w io.Writer, s *schema.Schema) error {
+	out := newWriter(w)
+	path := g.namer(s)
+
+	out.printf("# %s schema, generated by dbv on %s\n", s.Database, s.GeneratedAt.Format("2006-01-02 15:04:05"))
+	out.printf("direction: %s\n", g.direction)
+
+	// Tables and views are declared inside the container of their schema,
+	// so they are grouped by schema first.
+	var order []string
+	shapes := make(map[string][]d2Shape)
+	add := func(shape d2Shape) {
+		if _, ok := shapes[shape.schema]; !ok {
+			order = append(order, shape.schema)
+		}
+		shapes[shape.schema] = append(shapes[shape.schema], shape)
+	}
+
+	columns := make(map[string]map[string]bool)
+	for _, table := range s.Tables {
+		names := make(map[string]bool)
+		for _, col := range table.Columns {
+			names[col.Name] = true
+		}
+		columns[table.Schema+"\x00"+table.Name] = names
+
+		shape := d2Shape{schema: table.Schema, name: table.Name, columns: table.Columns,
+			primaryKeys: table.PrimaryKeys, comment: table.Comment}
+		if table.Collapsed {
+			shape.style = "style.opacity: 0.6"
+		}
+		add(shape)
+	}
+	for _, view := range s.Views {
+		add(d2Shape{schema: view.Schema, name: view.Name, columns: view.Columns, comment: view.Comment, style: "style.stroke-dash: 3"})
+	}
+
+	for _, schemaName := range order {
+		container := path.containers && schemaName != ""
+		indent := ""
+		if container {
+			out.printf("\n%s: {\n", d2Quote(schemaName))
+			indent = "  "
+		}
+		for i, shape := range shapes[schemaName] {
+			if i > 0 || !container {
+				out.print("\n")
+			}
+			writeD2Table(out, s, indent, path.key(shape.schema, shape.name), shape)
+		}
+		if container {
+			out.print("}\n")
+		}
+	}
+
+	if len(s.ForeignKeys) > 0 {
+		out.print("\n")
+	}
+	for _, ref := range dbmlRefs(s) {
+		fk := ref.fk
+		from, ok := columns[fk.Schema+"\x00"+fk.Table]
+		if !ok {
+			continue
+		}
+		to, ok := columns[fk.ReferencedSchema+"\x00"+fk.ReferencedTable]
+		if !ok {
+			continue
+		}
+
+		source := "cf-many"
+		if oneToOne(s, fk.Schema, fk.Table, ref.columns) {
+			source = "cf-one"
+		}
+		if !anyNullable(s, fk.Schema, fk.Table, ref.columns) {
+			source += "-required"
+		}
+
+		for i, col := range ref.columns {
+			a := path.object(fk.Schema, fk.Table)
+			if from[col] {
+				a += "." + d2Quote(col)
+			}
+			b := path.object(fk.ReferencedSchema, fk.ReferencedTable)
+			if to[ref.targets[i]] {
+				b += "." + d2Quote(ref.targets[i])
+			}
+			out.printf("%s -> %s: {source-arrowhead.shape: %s; target-arrowhead.shape: cf-one-required}\n", a, b, source)
+		}
+	}
+
+	return out.flush()
+}
+
+// d2Shape is a table or view drawn as a sql_table.
+type d2Shape struct {
+	schema      string
+	name        string
+	columns     []schema.Column
+	primaryKeys []string
+	comment     string
+	style       string
+}
+
+func writeD2Table(out *writer, s *schema.Schema, indent, key string, shape d2Shape) {
+	foreign := make(map[string]bool)
+	for _, fk := range s.ForeignKeys {
+		if fk.Schema == shape.schema && fk.Table == shape.name {
+			foreign[fk.Column] = true
+		}
+	}
+	// PostgreSQL gives primary keys and unique constraints only on the table
+	// and in its indexes, not on the columns.
+	unique := make(map[string]bool)
+	for _, index := range s.Indexes {
+		if index.IsUnique && !index.IsPrimary && len(index.Columns) == 1 &&
+			index.Table == shape.name && (index.Schema == "" || index.Schema == shape.schema) {
+			unique[index.Columns[0]] = true
+		}
+	}
+
+	out.printf("%s%s: {\n", indent, key)
+	out.printf("%s  shape: sql_table\n", indent)
+	if shape.comment != "" {
+		out.printf("%s  tooltip: %s\n", indent, d2String(shape.comment))
+	}
+	if shape.style != "" {
+		out.printf("%s  %s\n", indent, shape.style)
+	}
+
+	for _, col := range shape.columns {
+		var constraints []string
+		if col.IsPrimaryKey || slices.Contains(shape.primaryKeys, col.Name) {
+			constraints = append(constraints, "primary_key")
+		}
+		if foreign[col.Name] {
+			constraints = append(constraints, "foreign_key")
+		}
+		if col.IsUnique || unique[col.Name] {
+			constraints = append(constraints, "unique")
+		}
+
+		out.printf("%s  %s: %s", indent, d2Quote(col.Name), d2Value(layout.ColumnType(col)))
+		switch len(constraints) {
+		case 0:
+		case 1:
+			out.printf(" {constraint: %s}", constraints[0])
+		default:
+			out.printf(" {constraint: [%s]}", strings.Join(constraints, "; "))
+		}
+		out.print("\n")
+	}
+	out.printf("%s}\n", indent)
+}
+
+// d2Paths names the shapes of a schema, either as keys inside a container
+// per schema or as top-level keys qualified like the other formats.
+type d2Paths struct {
+	containers bool
+	qualify    func(schemaName, name string) string
+}
+
+func (g *d2Generator) namer(s *schema.Schema) d2Paths {
+	// The qualifier only adds schema names when there are several schemas.
+	qualify := s.Qualifier()
+	multiple := qualify("schema", "name") != "name"
+	return d2Paths{containers: g.containers && multiple, qualify: qualify}
+}
+
+// key returns the key of a shape within its container.
+func (p d2Paths) key(schemaName, name string) string {
+	if p.containers && schemaName != "" {
+		return d2Quote(name)
+	}
+	return d2Quote(p.qualify(schemaName, name))
+}
+
+// object returns the full path of a shape, for connections.
+func (p d2Paths) object(schemaName, name string) string {
+	if p.containers && schemaName != "" {
+		return d2Quote(schemaName) + "." + d2Quote(name)
+	}
+	return d2Quote(p.qualify(schemaName, name))
+}
+
+// anyNullable reports whether any of the columns of the table is nullable,
+// which makes a reference through them optional.
+func anyNullable(s *schema.Schema, schemaName, tableName string, columns []string) bool {
+	for _, table := range s.Tables {
+		if table.Schema != schemaName || table.Name != tableName {
+			continue
+		}
+		for _, col := range table.Columns {
+			if col.IsNullable && slices.Contains(columns, col.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var d2BareName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// d2Keywords are the keys that D2 reads as attributes rather than as
+// objects or columns, unless they are quoted.
+var d2Keywords = map[string]bool{
+	"_": true, "label": true, "desc": true, "shape": true, "icon": true,
+	"constraint": true, "tooltip": true, "link": true, "near": true,
+	"width": true, "height": true, "direction": true, "top": true,
+	"left": true, "grid-rows": true, "grid-columns": true, "grid-gap": true,
+	"vertical-gap": true, "horizontal-gap": true, "class": true,
+	"classes": true, "vars": true, "style": true, "source-arrowhead": true,
+	"target-arrowhead": true, "null": true,
+}
+
+// d2Quote double-quotes a key unless it is a plain identifier that D2 does
+// not treat as a keyword.
+func d2Quote(name string) string {
+	if d2BareName.MatchString(name) && !d2Keywords[strings.ToLower(name)] {
+		return name
+	}
+	return d2String(name)
+}
+
+// d2Value formats a column type as the label of a sql_table row.
+func d2Value(typ string) string {
+	if typ == "" {
+		return `""`
+	}
+	return d2Quote(typ)
+}
+
+func d2String(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "").Replace(s) + `"`
+}
@@ -0,0 +1,72 @@
+package generators
+
+import (
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
+	"strings"
+	"testing"
+)
+
+func TestD2(t *testing.T) {
+	out := generate(t, "d2", nil, refSchema())
+	for _, want := range []string{
+		"direction: right\n",
+		"orders: {\n  shape: sql_table\n  tooltip: \"Placed orders\"\n  id: integer {constraint: primary_key}\n",
+		"  user_id: integer {constraint: foreign_key}\n",
+		"orders.user_id -> users.id: {source-arrowhead.shape: cf-many; target-arrowhead.shape: cf-one-required}\n",
+		"orders.created_by -> users.id: {source-arrowhead.shape: cf-many-required; target-arrowhead.shape: cf-one-required}\n",
+		"orders.account_id -> accounts.id: {",
+		"orders.account_region -> accounts.region: {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, " -> "); n != 4 {
+		t.Errorf("output has %d connections, want 4:\n%s", n, out)
+	}
+}
+
+func TestD2Containers(t *testing.T) {
+	s := refSchema()
+	s.Tables = append(s.Tables, schema.Table{Name: "label", Schema: "audit", Columns: []schema.Column{{Name: "style", Type: "text"}}})
+	s.ForeignKeys = append(s.ForeignKeys, schema.ForeignKey{Schema: "audit", Table: "label", Column: "style",
+		ReferencedSchema: "main", ReferencedTable: "users", ReferencedColumn: "id"})
+
+	out := generate(t, "d2", generator.Options{"direction": "down"}, s)
+	for _, want := range []string{
+		"direction: down\n",
+		"main: {\n  users: {\n",
+		"audit: {\n  \"label\": {\n    shape: sql_table\n    \"style\": text {constraint: foreign_key}\n  }\n}\n",
+		"audit.\"label\".\"style\" -> main.users.id: {",
+		"main.orders.user_id -> main.users.id: {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	out = generate(t, "d2", generator.Options{"containers": "false"}, s)
+	if !strings.Contains(out, "\"audit.label\".\"style\" -> \"main.users\".id: {") {
+		t.Errorf("output without containers lacks qualified names:\n%s", out)
+	}
+}
+
+func TestD2InvalidDirection(t *testing.T) {
+	if _, err := generator.New("d2", generator.Options{"direction": "sideways"}); err == nil {
+		t.Error("New accepted an invalid direction")
+	}
+}
+
+func TestD2PostgreSQL(t *testing.T) {
+	out := generate(t, "d2", nil, pgSchema())
+	for _, want := range []string{
+		"users: {\n  shape: sql_table\n  id: integer {constraint: primary_key}\n  email: text {constraint: unique}\n  nickname: text\n}\n",
+		"  user_id: integer {constraint: [primary_key; foreign_key]}\n  team_id: integer {constraint: primary_key}\n",
+		"memberships.user_id -> users.id: {source-arrowhead.shape: cf-many-required; target-arrowhead.shape: cf-one-required}\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
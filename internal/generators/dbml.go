@@ -0,0 +1,446 @@
+package generators
+
+import (
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+func init() {
+	generator.Register("dbml", newDBML)
+}
+
+// dbmlGenerator renders DBML, the language of dbdiagram.io: enums, tables
+// with their indexes, references and table groups. Views have no DBML
+// equivalent and are left out.
+type dbmlGenerator struct {
+	groups string
+}
+
+func newDBML(opts generator.Options) (generator.Generator, error) {
+	groups := strings.ToLower(opts.String("groups", "schema"))
+	switch groups {
+	case "schema", "prefix", "none":
+	default:
+		return nil, fmt.Errorf("option groups: invalid value '%s'. Valid values: schema, prefix, none", groups)
+	}
+	return &dbmlGenerator{groups: groups}, nil
+}
+
+func (g *dbmlGenerator) Name() string        { return "dbml" }
+func (g *dbmlGenerator) Description() string { return "DBML (dbdiagram.io)" }
+func (g *dbmlGenerator) Extension() string   { return ".dbml" }
+
+func (g *dbmlGenerator) Options() []generator.Option {
+	return []generator.Option{
+		{Name: "groups", Description: "TableGroups: schema (when there are several), prefix (name before the first _), none", Default: "schema"},
+	}
+}
+
+func (g *dbmlGenerator) Generate(w io.Writer, s *schema.Schema) error {
+	out := newWriter(w)
+	name := dbmlNamer(s)
+
+	out.printf("// %s schema, generated by dbv on %s\n", s.Database, s.GeneratedAt.Format("2006-01-02 15:04:05"))
+
+	enums := make(map[string]string)
+	for _, enum := range s.Enums {
+		enums[enum.Name] = name(enum.Schema, enum.Name)
+		enums[enum.Schema+"."+enum.Name] = name(enum.Schema, enum.Name)
+
+		out.printf("\nEnum %s {\n", name(enum.Schema, enum.Name))
+		for _, value := range enum.Values {
+			out.printf("  %s\n", dbmlQuote(value))
+		}
+		if enum.Comment != "" {
+			out.printf("  Note: %s\n", dbmlString(enum.Comment))
+		}
+		out.print("}\n")
+	}
+
+	for _, table := range s.Tables {
+		g.writeTable(out, s, table, name, enums)
+	}
+
+	for _, ref := range dbmlRefs(s) {
+		g.writeRef(out, s, ref, name)
+	}
+
+	for _, group := range g.tableGroups(s) {
+		out.printf("\nTableGroup %s {\n", dbmlQuote(group.name))
+		for _, table := range group.tables {
+			out.printf("  %s\n", name(table.Schema, table.Name))
+		}
+		out.print("}\n")
+	}
+
+	return out.flush()
+}
+
+// dbmlNamer returns a function that writes quoted, schema-qualified names
+// when the schema spans several schemas and plain quoted names otherwise.
+func dbmlNamer(s *schema.Schema) func(schemaName, name string) string {
+	qualify := s.Qualifier()
+	return func(schemaName, name string) string {
+		if schemaName != "" && qualify(schemaName, name) != name {
+			return dbmlQuote(schemaName) + "." + dbmlQuote(name)
+		}
+		return dbmlQuote(name)
+	}
+}
+
+func (g *dbmlGenerator) writeTable(out *writer, s *schema.Schema, table schema.Table, name func(string, string) string, enums map[string]string) {
+	out.printf("\nTable %s {\n", name(table.Schema, table.Name))
+
+	// PostgreSQL gives the primary key only on the table, not on its columns.
+	singlePK := len(table.PrimaryKeys) == 1
+	for _, col := range table.Columns {
+		pk := singlePK && table.PrimaryKeys[0] == col.Name
+		var settings []string
+		if pk {
+			settings = append(settings, "pk")
+		}
+		if !col.IsNullable && !pk {
+			settings = append(settings, "not null")
+		}
+		if col.IsUnique {
+			settings = append(settings, "unique")
+		}
+		if col.DefaultValue != nil {
+			settings = append(settings, "default: "+dbmlDefault(*col.DefaultValue))
+		}
+		if col.Comment != "" {
+			settings = append(settings, "note: "+dbmlString(col.Comment))
+		}
+
+		typ, ok := enums[col.Type]
+		if !ok {
+			typ = dbmlType(col)
+		}
+		out.printf("  %s %s", dbmlQuote(col.Name), typ)
+		if len(settings) > 0 {
+			out.printf(" [%s]", strings.Join(settings, ", "))
+		}
+		out.print("\n")
+	}
+
+	var indexes []schema.Index
+	for _, index := range s.Indexes {
+		if index.Table != table.Name || (index.Schema != "" && index.Schema != table.Schema) {
+			continue
+		}
+		// A single-column primary key is already marked on its column.
+		if index.IsPrimary && singlePK {
+			continue
+		}
+		// SQLite does not give the text of an indexed expression.
+		if slices.Contains(index.Columns, "(expression)") {
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+	if len(table.PrimaryKeys) > 1 && !hasPrimaryIndex(indexes) {
+		indexes = append([]schema.Index{{Columns: table.PrimaryKeys, IsPrimary: true}}, indexes...)
+	}
+
+	if len(indexes) > 0 {
+		out.print("\n  indexes {\n")
+		for _, index := range indexes {
+			columns := make([]string, len(index.Columns))
+			for i, col := range index.Columns {
+				columns[i] = dbmlIndexColumn(col)
+			}
+			target := columns[0]
+			if len(columns) > 1 {
+				target = "(" + strings.Join(columns, ", ") + ")"
+			}
+
+			var settings []string
+			switch {
+			case index.IsPrimary:
+				settings = append(settings, "pk")
+			case index.IsUnique:
+				settings = append(settings, "unique")
+			}
+			if index.Name != "" && !index.IsPrimary {
+				settings = append(settings, "name: "+dbmlString(index.Name))
+			}
+			if index.Type == "btree" || index.Type == "hash" {
+				settings = append(settings, "type: "+index.Type)
+			}
+
+			out.printf("    %s", target)
+			if len(settings) > 0 {
+				out.printf(" [%s]", strings.Join(settings, ", "))
+			}
+			out.print("\n")
+		}
+		out.print("  }\n")
+	}
+
+	if table.Comment != "" {
+		out.printf("\n  Note: %s\n", dbmlString(table.Comment))
+	}
+
+	out.print("}\n")
+}
+
+func hasPrimaryIndex(indexes []schema.Index) bool {
+	for _, index := range indexes {
+		if index.IsPrimary {
+			return true
+		}
+	}
+	return false
+}
+
+// dbmlRef is a foreign key with all its columns.
+type dbmlRef struct {
+	fk      schema.ForeignKey
+	columns []string
+	targets []string
+}
+
+// dbmlRefs groups the columns of composite foreign keys, which the schema
+// lists one row per column under the same ordinal or, without one, the same
+// constraint name.
+func dbmlRefs(s *schema.Schema) []*dbmlRef {
+	type refKey struct {
+		schema, table, name string
+		ordinal             int
+	}
+
+	var refs []*dbmlRef
+	byKey := make(map[refKey]*dbmlRef)
+	for _, fk := range s.ForeignKeys {
+		key := refKey{schema: fk.Schema, table: fk.Table, ordinal: fk.Ordinal}
+		if fk.Ordinal == 0 {
+			key.name = fk.Name
+		}
+		if ref, ok := byKey[key]; ok && (fk.Name != "" || fk.Ordinal != 0) {
+			ref.columns = append(ref.columns, fk.Column)
+			ref.targets = append(ref.targets, fk.ReferencedColumn)
+			continue
+		}
+		ref := &dbmlRef{fk: fk, columns: []string{fk.Column}, targets: []string{fk.ReferencedColumn}}
+		byKey[key] = ref
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+func (g *dbmlGenerator) writeRef(out *writer, s *schema.Schema, ref *dbmlRef, name func(string, string) string) {
+	fk := ref.fk
+	endpoint := func(schemaName, table string, columns []string) string {
+		quoted := make([]string, len(columns))
+		for i, col := range columns {
+			quoted[i] = dbmlQuote(col)
+		}
+		if len(quoted) == 1 {
+			return name(schemaName, table) + "." + quoted[0]
+		}
+		return name(schemaName, table) + ".(" + strings.Join(quoted, ", ") + ")"
+	}
+
+	// A reference is one-to-one when the referencing columns are unique.
+	relation := ">"
+	if oneToOne(s, fk.Schema, fk.Table, ref.columns) {
+		relation = "-"
+	}
+
+	var settings []string
+	if action := dbmlAction(fk.OnDelete); action != "" {
+		settings = append(settings, "delete: "+action)
+	}
+	if action := dbmlAction(fk.OnUpdate); action != "" {
+		settings = append(settings, "update: "+action)
+	}
+
+	out.print("\nRef")
+	if fk.Name != "" {
+		out.printf(" %s", dbmlQuote(fk.Name))
+	}
+	out.printf(": %s %s %s", endpoint(fk.Schema, fk.Table, ref.columns), relation,
+		endpoint(fk.ReferencedSchema, fk.ReferencedTable, ref.targets))
+	if len(settings) > 0 {
+		out.printf(" [%s]", strings.Join(settings, ", "))
+	}
+	out.print("\n")
+}
+
+// oneToOne reports whether columns of the table are its primary key or
+// covered by a unique constraint or index.
+func oneToOne(s *schema.Schema, schemaName, tableName string, columns []string) bool {
+	sameColumns := func(a, b []string) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		a, b = append([]string{}, a...), append([]string{}, b...)
+		sort.Strings(a)
+		sort.Strings(b)
+		return strings.Join(a, "\x00") == strings.Join(b, "\x00")
+	}
+
+	for _, table := range s.Tables {
+		if table.Schema != schemaName || table.Name != tableName {
+			continue
+		}
+		if sameColumns(table.PrimaryKeys, columns) {
+			return true
+		}
+		if len(columns) == 1 {
+			for _, col := range table.Columns {
+				if col.Name == columns[0] && col.IsUnique {
+					return true
+				}
+			}
+		}
+	}
+	for _, index := range s.Indexes {
+		if index.IsUnique && index.Table == tableName && (index.Schema == "" || index.Schema == schemaName) &&
+			sameColumns(index.Columns, columns) {
+			return true
+		}
+	}
+	return false
+}
+
+type dbmlGroup struct {
+	name   string
+	tables []schema.Table
+}
+
+func (g *dbmlGenerator) tableGroups(s *schema.Schema) []dbmlGroup {
+	var key func(table schema.Table) string
+	switch g.groups {
+	case "schema":
+		key = func(table schema.Table) string { return table.Schema }
+	case "prefix":
+		key = func(table schema.Table) string {
+			prefix, _, found := strings.Cut(table.Name, "_")
+			if !found {
+				return ""
+			}
+			return prefix
+		}
+	default:
+		return nil
+	}
+
+	var groups []dbmlGroup
+	index := make(map[string]int)
+	for _, table := range s.Tables {
+		k := key(table)
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, dbmlGroup{name: k})
+		}
+		groups[i].tables = append(groups[i].tables, table)
+	}
+
+	// Schema groups only help when there is more than one; prefix groups
+	// need at least two tables to group.
+	var result []dbmlGroup
+	for _, group := range groups {
+		if group.name == "" || (g.groups == "schema" && len(groups) < 2) || (g.groups == "prefix" && len(group.tables) < 2) {
+			continue
+		}
+		result = append(result, group)
+	}
+	return result
+}
+
+var dbmlBareName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// dbmlQuote double-quotes a name unless it is a plain identifier.
+func dbmlQuote(name string) string {
+	if dbmlBareName.MatchString(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `\"`) + `"`
+}
+
+// dbmlString writes a string literal, triple-quoted when it spans lines.
+func dbmlString(s string) string {
+	if strings.Contains(s, "\n") {
+		return "'''" + strings.ReplaceAll(s, "'''", `\'''`) + "'''"
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+var dbmlBareType = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\([0-9, ]+\))?(\[\])?$`)
+
+func dbmlType(col schema.Column) string {
+	t := col.Type
+	switch {
+	case col.Length != nil && *col.Length > 0:
+		t = fmt.Sprintf("%s(%d)", t, *col.Length)
+	case col.Precision != nil && col.Scale != nil && isDecimal(col.Type):
+		t = fmt.Sprintf("%s(%d,%d)", t, *col.Precision, *col.Scale)
+	}
+	if t == "" {
+		t = "unknown"
+	}
+	if dbmlBareType.MatchString(t) {
+		return t
+	}
+	return `"` + strings.ReplaceAll(t, `"`, `\"`) + `"`
+}
+
+func isDecimal(typ string) bool {
+	switch strings.ToLower(typ) {
+	case "decimal", "numeric":
+		return true
+	}
+	return false
+}
+
+var (
+	dbmlNumber  = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+	dbmlLiteral = regexp.MustCompile(`^'((?:[^']|'')*)'(::[A-Za-z0-9_ ."\[\]]+)?$`)
+)
+
+// dbmlDefault converts a column default to a DBML default value: numbers,
+// booleans and null as they are, string literals (without a Postgres cast)
+// quoted, and anything else as a backtick expression.
+func dbmlDefault(value string) string {
+	value = strings.TrimSpace(value)
+	switch {
+	case dbmlNumber.MatchString(value):
+		return value
+	case strings.EqualFold(value, "true"), strings.EqualFold(value, "false"), strings.EqualFold(value, "null"):
+		return strings.ToLower(value)
+	}
+	if m := dbmlLiteral.FindStringSubmatch(value); m != nil {
+		return dbmlString(strings.ReplaceAll(m[1], "''", "'"))
+	}
+	return "`" + strings.ReplaceAll(value, "`", "'") + "`"
+}
+
+// dbmlAction converts a referential action, omitting the default.
+func dbmlAction(action string) string {
+	action = strings.ToLower(strings.TrimSpace(action))
+	switch action {
+	case "cascade", "restrict", "set null", "set default":
+		return action
+	}
+	return ""
+}
+
+// dbmlIndexColumn writes an index column, or an expression in backticks.
+func dbmlIndexColumn(col string) string {
+	if dbmlBareName.MatchString(col) || (len(col) > 1 && strings.HasPrefix(col, `"`) && strings.HasSuffix(col, `"`)) {
+		return col
+	}
+	if strings.ContainsAny(col, "()") || strings.Contains(col, " ") {
+		return "`" + strings.ReplaceAll(col, "`", "'") + "`"
+	}
+	return dbmlQuote(col)
+}
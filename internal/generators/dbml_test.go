@@ -0,0 +1,161 @@
+package generators
+
+import (
+	"bytes"
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// refSchema is a SQLite-like schema: orders references users twice through
+// keys with the same made-up name, and accounts through a composite key.
+func refSchema() *schema.Schema {
+	column := func(name string, pk, nullable bool) schema.Column {
+		return schema.Column{Name: name, Type: "INTEGER", IsPrimaryKey: pk, IsNullable: nullable}
+	}
+	fk := func(ordinal int, name, col, table, target string) schema.ForeignKey {
+		return schema.ForeignKey{Name: name, Schema: "main", Table: "orders", Column: col,
+			ReferencedSchema: "main", ReferencedTable: table, ReferencedColumn: target, OnDelete: "CASCADE", Ordinal: ordinal}
+	}
+	return &schema.Schema{
+		Database:    "sqlite",
+		GeneratedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Tables: []schema.Table{
+			{Name: "users", Schema: "main", PrimaryKeys: []string{"id"}, Columns: []schema.Column{column("id", true, false)}},
+			{Name: "accounts", Schema: "main", PrimaryKeys: []string{"id", "region"}, Columns: []schema.Column{
+				column("id", true, false), {Name: "region", Type: "TEXT", IsPrimaryKey: true},
+			}},
+			{Name: "orders", Schema: "main", PrimaryKeys: []string{"id"}, Comment: "Placed orders", Columns: []schema.Column{
+				column("id", true, false),
+				column("user_id", false, true),
+				column("created_by", false, false),
+				column("account_id", false, false),
+				{Name: "account_region", Type: "TEXT"},
+			}},
+		},
+		ForeignKeys: []schema.ForeignKey{
+			fk(1, "fk_orders_user", "user_id", "users", "id"),
+			fk(2, "fk_orders_user", "created_by", "users", "id"),
+			fk(3, "fk_orders_account_id", "account_id", "accounts", "id"),
+			fk(3, "fk_orders_account_id", "account_region", "accounts", "region"),
+		},
+	}
+}
+
+func generate(t *testing.T, format string, opts generator.Options, s *schema.Schema) string {
+	t.Helper()
+	g, err := generator.New(format, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := g.Generate(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestDBMLRefs(t *testing.T) {
+	var got []string
+	for _, ref := range dbmlRefs(refSchema()) {
+		got = append(got, fmt.Sprintf("%s->%s", strings.Join(ref.columns, ","), strings.Join(ref.targets, ",")))
+	}
+	want := []string{"user_id->id", "created_by->id", "account_id,account_region->id,region"}
+	if !slices.Equal(got, want) {
+		t.Errorf("refs = %v, want %v", got, want)
+	}
+
+	// Without ordinals, as from PostgreSQL, the name groups the columns.
+	s := refSchema()
+	for i := range s.ForeignKeys {
+		s.ForeignKeys[i].Ordinal = 0
+	}
+	if refs := dbmlRefs(s); len(refs) != 2 || len(refs[1].columns) != 2 {
+		t.Errorf("refs grouped by name = %d, want 2 with a composite one", len(refs))
+	}
+}
+
+func TestDBML(t *testing.T) {
+	out := generate(t, "dbml", nil, refSchema())
+	for _, want := range []string{
+		"Table orders {\n  id INTEGER [pk]\n  user_id INTEGER\n  created_by INTEGER [not null]\n",
+		"  Note: 'Placed orders'\n",
+		"    (id, region) [pk]\n",
+		"Ref fk_orders_user: orders.user_id > users.id [delete: cascade]\n",
+		"Ref fk_orders_user: orders.created_by > users.id [delete: cascade]\n",
+		"Ref fk_orders_account_id: orders.(account_id, account_region) > accounts.(id, region) [delete: cascade]\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+// pgSchema is shaped like the output of the PostgreSQL extractor: primary
+// keys and unique constraints are only on the table and in its indexes, never
+// on the columns.
+func pgSchema() *schema.Schema {
+	column := func(name, typ string) schema.Column {
+		return schema.Column{Name: name, Type: typ}
+	}
+	index := func(table, name string, unique, primary bool, columns ...string) schema.Index {
+		return schema.Index{Schema: "public", Table: table, Name: name, Columns: columns,
+			IsUnique: unique, IsPrimary: primary, Type: "btree"}
+	}
+	return &schema.Schema{
+		Database: "postgresql",
+		Tables: []schema.Table{
+			{Name: "users", Schema: "public", PrimaryKeys: []string{"id"}, Columns: []schema.Column{
+				column("id", "integer"), column("email", "text"), {Name: "nickname", Type: "text", IsNullable: true},
+			}},
+			{Name: "memberships", Schema: "public", PrimaryKeys: []string{"user_id", "team_id"}, Columns: []schema.Column{
+				column("user_id", "integer"), column("team_id", "integer"),
+			}},
+		},
+		Indexes: []schema.Index{
+			index("memberships", "memberships_pkey", true, true, "user_id", "team_id"),
+			index("users", "users_email_key", true, false, "email"),
+			index("users", "users_lower_nickname_idx", false, false, "lower(nickname)"),
+			index("users", "users_pkey", true, true, "id"),
+		},
+		ForeignKeys: []schema.ForeignKey{
+			{Name: "memberships_user_id_fkey", Schema: "public", Table: "memberships", Column: "user_id",
+				ReferencedSchema: "public", ReferencedTable: "users", ReferencedColumn: "id"},
+		},
+	}
+}
+
+func TestDBMLPostgreSQL(t *testing.T) {
+	out := generate(t, "dbml", nil, pgSchema())
+	for _, want := range []string{
+		"Table users {\n  id integer [pk]\n  email text [not null]\n  nickname text\n",
+		"    email [unique, name: 'users_email_key', type: btree]\n",
+		"    `lower(nickname)` [name: 'users_lower_nickname_idx', type: btree]\n",
+		"    (user_id, team_id) [pk, type: btree]\n",
+		"Ref memberships_user_id_fkey: memberships.user_id > users.id\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "users_pkey") || strings.Count(out, "[pk") != 2 {
+		t.Errorf("output does not have exactly one primary key per table:\n%s", out)
+	}
+}
+
+func TestDBMLExpressionIndex(t *testing.T) {
+	s := refSchema()
+	s.Indexes = []schema.Index{
+		{Schema: "main", Table: "orders", Name: "orders_expr", Columns: []string{"(expression)"}},
+		{Schema: "main", Table: "orders", Name: "orders_user", Columns: []string{"user_id", "(expression)"}},
+		{Schema: "main", Table: "orders", Name: "orders_created_by", Columns: []string{"created_by"}},
+	}
+	out := generate(t, "dbml", nil, s)
+	if strings.Contains(out, "expression") || !strings.Contains(out, "created_by [name: 'orders_created_by']") {
+		t.Errorf("output keeps an expression index or drops a column index:\n%s", out)
+	}
+}
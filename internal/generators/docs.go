@@ -0,0 +1,415 @@
+package generators
+
+import (
+	"bufio"
+	"dbv/internal/layout"
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
+	_ "embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+func init() {
+	generator.Register("docs", newDocs)
+}
+
+var (
+	//go:embed docs.html.tmpl
+	docsHTMLSource string
+	//go:embed docs.md.tmpl
+	docsMarkdownSource string
+)
+
+var (
+	docsHTMLTemplate = htmltemplate.Must(htmltemplate.New("docs").Funcs(htmltemplate.FuncMap{
+		"join": strings.Join,
+	}).Parse(docsHTMLSource))
+	docsMarkdownTemplate = template.Must(template.New("docs").Funcs(template.FuncMap{
+		"join": strings.Join,
+		"cell": markdownCell,
+	}).Parse(docsMarkdownSource))
+)
+
+// docsStyle is written to style.css for the HTML pages.
+const docsStyle = `body { margin: 0 auto; max-width: 1100px; padding: 16px 24px 48px; font: 15px/1.5 system-ui, -apple-system, "Segoe UI", sans-serif; color: #1f2937; }
+a { color: #2563eb; text-decoration: none; }
+a:hover { text-decoration: underline; }
+nav { font-size: 14px; margin-bottom: 8px; }
+h1 { margin: 0 0 4px; word-break: break-all; }
+h2 { margin-top: 32px; font-size: 18px; border-bottom: 1px solid #e5e7eb; padding-bottom: 4px; }
+.muted { color: #6b7280; }
+table { border-collapse: collapse; width: 100%; font-size: 14px; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #e5e7eb; vertical-align: top; }
+th { background: #f8fafc; }
+code, pre, .mono { font-family: ui-monospace, Menlo, Consolas, monospace; font-size: 13px; }
+pre { background: #f8fafc; padding: 12px; overflow: auto; white-space: pre-wrap; }
+.badge { display: inline-block; padding: 0 4px; border-radius: 3px; font-size: 11px; background: #fef3c7; color: #92400e; margin-right: 2px; }
+.diagram { overflow: auto; border: 1px solid #e5e7eb; padding: 8px; }
+.diagram img { max-width: 100%; }
+`
+
+// docsGenerator writes a browsable documentation site: an index page and a
+// page per table and view with its columns, keys, indexes, relationships and
+// a diagram of its direct neighbours. Pages are static HTML, or Markdown for
+// site generators such as MkDocs or Hugo.
+type docsGenerator struct {
+	markdown bool
+	diagrams bool
+}
+
+func newDocs(opts generator.Options) (generator.Generator, error) {
+	g := &docsGenerator{}
+	switch markup := strings.ToLower(opts.String("markup", "html")); markup {
+	case "html":
+	case "markdown", "md":
+		g.markdown = true
+	default:
+		return nil, fmt.Errorf("option markup: invalid value '%s'. Valid values: html, markdown", markup)
+	}
+
+	var err error
+	if g.diagrams, err = opts.Bool("diagrams", true); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *docsGenerator) Name() string        { return "docs" }
+func (g *docsGenerator) Description() string { return "Documentation site (directory)" }
+func (g *docsGenerator) Extension() string   { return "" }
+
+func (g *docsGenerator) Options() []generator.Option {
+	return []generator.Option{
+		{Name: "markup", Description: "Page format: html, or markdown for MkDocs, Hugo and similar", Default: "html"},
+		{Name: "diagrams", Description: "Draw a diagram of each table's direct relationships", Default: "true"},
+	}
+}
+
+func (g *docsGenerator) Generate(w io.Writer, s *schema.Schema) error {
+	return errors.New("the documentation site is a directory of files and cannot be written as one document")
+}
+
+// docsSite is the data of the index page.
+type docsSite struct {
+	Title         string
+	Database      string
+	GeneratedAt   time.Time
+	Relationships int
+	// Heading is the Markdown heading of the sections of an object.
+	Heading string
+	// Diagram is the file of the whole schema's diagram, if drawn.
+	Diagram string
+	// Mermaid is a Mermaid diagram of the schema, if drawn.
+	Mermaid string
+	Tables  []*docsObject
+	Views   []*docsObject
+}
+
+// objects returns the tables followed by the views.
+func (s *docsSite) objects() []*docsObject {
+	return append(append([]*docsObject{}, s.Tables...), s.Views...)
+}
+
+// docsObject is the data of a table or view page. Links between pages are
+// relative: Path from the site root, Href from another object page.
+type docsObject struct {
+	Site         *docsSite
+	Title        string
+	Schema       string
+	Name         string
+	View         bool
+	Collapsed    bool
+	Comment      string
+	Path         string
+	Href         string
+	Diagram      string
+	Columns      []docsColumn
+	PrimaryKey   []string
+	Indexes      []schema.Index
+	References   []docsRelation
+	ReferencedBy []docsRelation
+	Definition   string
+}
+
+type docsColumn struct {
+	Name     string
+	Type     string
+	Nullable bool
+	Default  string
+	Comment  string
+	Keys     []string
+	// References are the foreign keys on the column.
+	References []docsRelation
+}
+
+// docsRelation is a foreign key seen from one of its tables: Column is on
+// that table, and Table and TargetColumn are on the other side.
+type docsRelation struct {
+	Name         string
+	Column       string
+	Table        string
+	Href         string
+	TargetColumn string
+	OnUpdate     string
+	OnDelete     string
+}
+
+func (g *docsGenerator) GenerateFiles(create generator.FileCreator, s *schema.Schema) error {
+	ext := ".html"
+	if g.markdown {
+		ext = ".md"
+	}
+	site := newDocsSite(s, s.Database+" schema", "##", func(dir, name string) (string, string) {
+		page := dir + "/" + name + ext
+		return page, "../" + page
+	})
+
+	if g.diagrams && len(site.Tables) > 0 {
+		site.Diagram = "schema.svg"
+	}
+	for _, object := range site.objects() {
+		if g.diagrams && (len(object.References) > 0 || len(object.ReferencedBy) > 0) {
+			object.Diagram = path.Base(strings.TrimSuffix(object.Path, ext)) + ".svg"
+		}
+	}
+
+	if !g.markdown {
+		if err := writeDocsFile(create, "style.css", func(w io.Writer) error {
+			_, err := io.WriteString(w, docsStyle)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	if site.Diagram != "" {
+		if err := writeDocsFile(create, site.Diagram, func(w io.Writer) error {
+			return (&svgGenerator{opts: layout.Options{Columns: false}}).Generate(w, s)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := writeDocsFile(create, "index"+ext, func(w io.Writer) error {
+		return g.execute(w, "index", site)
+	}); err != nil {
+		return err
+	}
+
+	for _, object := range site.objects() {
+		if object.Diagram != "" {
+			if err := writeDocsFile(create, strings.TrimSuffix(object.Path, ext)+".svg", func(w io.Writer) error {
+				return g.writeDiagram(w, s, object)
+			}); err != nil {
+				return err
+			}
+		}
+		if err := writeDocsFile(create, object.Path, func(w io.Writer) error {
+			return g.execute(w, "object", object)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *docsGenerator) execute(w io.Writer, name string, data any) error {
+	if g.markdown {
+		return docsMarkdownTemplate.ExecuteTemplate(w, name, data)
+	}
+	return docsHTMLTemplate.ExecuteTemplate(w, name, data)
+}
+
+func writeDocsFile(create generator.FileCreator, name string, write func(w io.Writer) error) error {
+	f, err := create(name)
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(f)
+	if err := write(out); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// writeDiagram draws object with the tables one relationship away, cut down
+// to their keys.
+func (g *docsGenerator) writeDiagram(w io.Writer, s *schema.Schema, object *docsObject) error {
+	name := object.Name
+	if object.Schema != "" {
+		name = object.Schema + "." + name
+	}
+	focused, err := s.Focus(schema.FocusOptions{
+		Tables:           []string{name},
+		Depth:            1,
+		Direction:        schema.Both,
+		CollapseBoundary: true,
+	})
+	if err != nil {
+		return err
+	}
+	return (&svgGenerator{opts: layout.Options{Columns: true, Types: true}}).Generate(w, focused)
+}
+
+// newDocsSite collects the tables and views of s, sorted by name. link
+// returns the path and the cross-reference link of an object from its kind,
+// "tables" or "views", and a file-name friendly name unique within the kind.
+func newDocsSite(s *schema.Schema, title, heading string, link func(dir, name string) (path, href string)) *docsSite {
+	qualify := s.Qualifier()
+	site := &docsSite{
+		Title:         title,
+		Heading:       heading,
+		Database:      s.Database,
+		GeneratedAt:   s.GeneratedAt,
+		Relationships: len(s.ForeignKeys),
+	}
+
+	names := make(map[string]bool)
+	pages := make(map[[2]string]*docsObject)
+	add := func(dir, schemaName, name string, view bool) *docsObject {
+		base := docsFileName(qualify(schemaName, name))
+		file := base
+		for n := 2; names[dir+"/"+strings.ToLower(file)]; n++ {
+			file = fmt.Sprintf("%s-%d", base, n)
+		}
+		names[dir+"/"+strings.ToLower(file)] = true
+
+		object := &docsObject{
+			Site:   site,
+			Title:  qualify(schemaName, name),
+			Schema: schemaName,
+			Name:   name,
+			View:   view,
+		}
+		object.Path, object.Href = link(dir, file)
+		pages[[2]string{schemaName, name}] = object
+		return object
+	}
+
+	for _, table := range s.Tables {
+		object := add("tables", table.Schema, table.Name, false)
+		object.Comment = table.Comment
+		object.Collapsed = table.Collapsed
+		object.PrimaryKey = table.PrimaryKeys
+		object.Columns = docsColumns(table.Columns)
+		site.Tables = append(site.Tables, object)
+	}
+	for _, view := range s.Views {
+		object := add("views", view.Schema, view.Name, true)
+		object.Comment = view.Comment
+		object.Definition = strings.TrimSpace(view.Definition)
+		object.Columns = docsColumns(view.Columns)
+		site.Views = append(site.Views, object)
+	}
+
+	for _, fk := range s.ForeignKeys {
+		from := pages[[2]string{fk.Schema, fk.Table}]
+		to := pages[[2]string{fk.ReferencedSchema, fk.ReferencedTable}]
+		if from != nil {
+			rel := docsRelation{
+				Name: fk.Name, Column: fk.Column,
+				Table: qualify(fk.ReferencedSchema, fk.ReferencedTable), TargetColumn: fk.ReferencedColumn,
+				OnUpdate: fk.OnUpdate, OnDelete: fk.OnDelete,
+			}
+			if to != nil {
+				rel.Href = to.Href
+			}
+			from.References = append(from.References, rel)
+			for i := range from.Columns {
+				if from.Columns[i].Name == fk.Column {
+					from.Columns[i].References = append(from.Columns[i].References, rel)
+					if !slices.Contains(from.Columns[i].Keys, "FK") {
+						from.Columns[i].Keys = append(from.Columns[i].Keys, "FK")
+					}
+				}
+			}
+		}
+		if to != nil {
+			rel := docsRelation{
+				Name: fk.Name, Column: fk.ReferencedColumn,
+				Table: qualify(fk.Schema, fk.Table), TargetColumn: fk.Column,
+				OnUpdate: fk.OnUpdate, OnDelete: fk.OnDelete,
+			}
+			if from != nil {
+				rel.Href = from.Href
+			}
+			to.ReferencedBy = append(to.ReferencedBy, rel)
+		}
+	}
+
+	for _, index := range s.Indexes {
+		for _, object := range site.Tables {
+			if object.Name == index.Table && (index.Schema == "" || index.Schema == object.Schema) {
+				object.Indexes = append(object.Indexes, index)
+			}
+		}
+	}
+
+	byTitle := func(objects []*docsObject) {
+		sort.SliceStable(objects, func(i, j int) bool {
+			return strings.ToLower(objects[i].Title) < strings.ToLower(objects[j].Title)
+		})
+	}
+	byTitle(site.Tables)
+	byTitle(site.Views)
+
+	return site
+}
+
+func docsColumns(columns []schema.Column) []docsColumn {
+	result := make([]docsColumn, 0, len(columns))
+	for _, col := range columns {
+		c := docsColumn{
+			Name:     col.Name,
+			Type:     layout.ColumnType(col),
+			Nullable: col.IsNullable,
+			Comment:  col.Comment,
+		}
+		if col.DefaultValue != nil {
+			c.Default = *col.DefaultValue
+		}
+		if col.IsPrimaryKey {
+			c.Keys = append(c.Keys, "PK")
+		}
+		if col.IsUnique {
+			c.Keys = append(c.Keys, "UQ")
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+// docsFileName makes a page file name from a table name.
+func docsFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, name)
+	if strings.Trim(name, ".") == "" {
+		return "_"
+	}
+	return name
+}
+
+// markdownCell escapes s for a Markdown table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
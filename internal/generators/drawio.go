@@ -0,0 +1,226 @@
+package generators
+
+import (
+	"dbv/internal/layout"
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+func init() {
+	generator.Register("drawio", newDrawio)
+}
+
+// drawioGenerator writes a diagrams.net (draw.io) file: a table shape per
+// table and view with a row per column, placed by the layout package, and ER
+// edges between the related rows. The shapes are plain draw.io cells, so the
+// diagram can be edited like one drawn by hand.
+type drawioGenerator struct {
+	opts layout.Options
+}
+
+func newDrawio(opts generator.Options) (generator.Generator, error) {
+	lopts, err := layoutOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &drawioGenerator{opts: lopts}, nil
+}
+
+func (g *drawioGenerator) Name() string                { return "drawio" }
+func (g *drawioGenerator) Description() string         { return "diagrams.net (draw.io)" }
+func (g *drawioGenerator) Extension() string           { return ".drawio" }
+func (g *drawioGenerator) Options() []generator.Option { return layoutOptionList }
+
+const (
+	drawioTableStyle = "shape=table;startSize=%s;container=1;collapsible=1;childLayout=tableLayout;fixedRows=1;rowLines=0;" +
+		"fontStyle=1;align=center;resizeLast=1;fillColor=%s;strokeColor=#64748b;swimlaneFillColor=#ffffff;fontFamily=Courier New;"
+	drawioRowStyle = "shape=tableRow;horizontal=0;startSize=0;swimlaneHead=0;swimlaneBody=0;fillColor=none;collapsible=0;" +
+		"dropTarget=0;points=[[0,0.5],[1,0.5]];portConstraint=eastwest;top=0;left=0;right=0;bottom=0;"
+	drawioCellStyle = "shape=partialRectangle;connectable=0;fillColor=none;top=0;left=0;bottom=0;right=0;overflow=hidden;" +
+		"whiteSpace=wrap;fontFamily=Courier New;"
+	drawioEdgeStyle = "edgeStyle=entityRelationEdgeStyle;endArrow=ERmandOne;startArrow=%s;endFill=0;startFill=0;" +
+		"strokeColor=#64748b;"
+)
+
+func (g *drawioGenerator) Generate(w io.Writer, s *schema.Schema) error {
+	d := layout.Compute(s, g.opts)
+	out := newWriter(w)
+
+	out.print(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	out.printf(`<mxfile host="dbv" modified="%s" agent="dbv" type="device" compressed="false">`+"\n",
+		s.GeneratedAt.UTC().Format("2006-01-02T15:04:05Z"))
+	out.printf(`  <diagram id="schema" name="%s">`+"\n", xmlEscape(s.Database))
+	out.printf(`    <mxGraphModel grid="1" gridSize="10" guides="1" tooltips="1" connect="1" arrows="1" fold="1" page="1" pageScale="1" pageWidth="%s" pageHeight="%s" math="0" shadow="0">`+"\n",
+		num(math.Ceil(d.Width)), num(math.Ceil(d.Height)))
+	out.print("      <root>\n")
+	out.print(`        <mxCell id="0"/>` + "\n")
+	out.print(`        <mxCell id="1" parent="0"/>` + "\n")
+
+	// Boxes follow the tables and then the views of the schema, and their
+	// rows the columns, which carry the comments.
+	rowIDs := make(map[[3]string]string)
+	tableIDs := make(map[[2]string]string)
+	for i, b := range d.Boxes {
+		var columns []schema.Column
+		var comment string
+		if i < len(s.Tables) {
+			columns, comment = s.Tables[i].Columns, s.Tables[i].Comment
+		} else {
+			columns, comment = s.Views[i-len(s.Tables)].Columns, s.Views[i-len(s.Tables)].Comment
+		}
+
+		id := fmt.Sprintf("t%d", i+1)
+		tableIDs[[2]string{b.Schema, b.Name}] = id
+		writeDrawioBox(out, id, b, columns, comment)
+
+		for j, row := range b.Rows {
+			rowIDs[[3]string{b.Schema, b.Name, row.Name}] = fmt.Sprintf("%s-r%d", id, j+1)
+		}
+	}
+
+	n := 0
+	for _, ref := range dbmlRefs(s) {
+		fk := ref.fk
+		from, okFrom := tableIDs[[2]string{fk.Schema, fk.Table}]
+		to, okTo := tableIDs[[2]string{fk.ReferencedSchema, fk.ReferencedTable}]
+		if !okFrom || !okTo {
+			continue
+		}
+
+		start := "ERoneToMany"
+		optional := anyNullable(s, fk.Schema, fk.Table, ref.columns)
+		switch one := oneToOne(s, fk.Schema, fk.Table, ref.columns); {
+		case one && optional:
+			start = "ERzeroToOne"
+		case one:
+			start = "ERmandOne"
+		case optional:
+			start = "ERzeroToMany"
+		}
+
+		// An edge per column pair joins the rows; without rows it joins the
+		// tables, once per constraint.
+		for i, col := range ref.columns {
+			source, target := from, to
+			if id, ok := rowIDs[[3]string{fk.Schema, fk.Table, col}]; ok {
+				source = id
+			}
+			if id, ok := rowIDs[[3]string{fk.ReferencedSchema, fk.ReferencedTable, ref.targets[i]}]; ok {
+				target = id
+			}
+			if i > 0 && source == from && target == to {
+				continue
+			}
+
+			n++
+			out.printf(`        <mxCell id="e%d" value="" style="%s" edge="1" parent="1" source="%s" target="%s">`+"\n",
+				n, xmlEscape(fmt.Sprintf(drawioEdgeStyle, start)), source, target)
+			out.print(`          <mxGeometry relative="1" as="geometry"/>` + "\n")
+			out.print("        </mxCell>\n")
+		}
+	}
+
+	out.print("      </root>\n")
+	out.print("    </mxGraphModel>\n")
+	out.print("  </diagram>\n")
+	out.print("</mxfile>\n")
+
+	return out.flush()
+}
+
+// writeDrawioBox writes the table shape of a box, with a row of three cells
+// (key, name and type) per column.
+func writeDrawioBox(out *writer, id string, b layout.Box, columns []schema.Column, comment string) {
+	fill := "#dbeafe"
+	switch {
+	case b.View:
+		fill = "#dcfce7"
+	case b.Collapsed:
+		fill = "#e5e7eb"
+	}
+	style := fmt.Sprintf(drawioTableStyle, num(layout.HeaderSize), fill)
+	if b.View {
+		style += "dashed=1;"
+	}
+
+	// The rows fill the box below the header, except for the padding the
+	// layout leaves at the bottom.
+	h := layout.HeaderSize + float64(len(b.Rows))*layout.RowHeight
+	if b.Collapsed {
+		h += layout.RowHeight
+	}
+
+	// Courier New advances like the layout metrics, but draw.io adds spacing
+	// around cell text, hence the extra room.
+	var name float64
+	types := false
+	for _, row := range b.Rows {
+		name = math.Max(name, layout.TextWidth(row.Name))
+		types = types || row.Type != ""
+	}
+	w := b.W + layout.Padding
+	keyW := math.Ceil(layout.TextWidth("PK ") + layout.Padding)
+	nameW := math.Ceil(name + 2*layout.Padding)
+	if !types {
+		nameW = w - keyW
+	}
+
+	writeDrawioCell(out, id, "1", b.Title, comment, style,
+		fmt.Sprintf(`x="%s" y="%s" width="%s" height="%s"`, num(b.X), num(b.Y), num(w), num(h)))
+
+	y := layout.HeaderSize
+	for j, row := range b.Rows {
+		rowID := fmt.Sprintf("%s-r%d", id, j+1)
+		geometry := fmt.Sprintf(`y="%s" width="%s" height="%s"`, num(y), num(w), num(layout.RowHeight))
+		writeDrawioCell(out, rowID, id, "", "", drawioRowStyle, geometry)
+
+		nameStyle := drawioCellStyle + "align=left;spacingLeft=4;"
+		if row.Key == "PK" {
+			nameStyle += "fontStyle=4;"
+		}
+		var columnComment string
+		if j < len(columns) {
+			columnComment = columns[j].Comment
+		}
+
+		writeDrawioCell(out, rowID+"-k", rowID, row.Key, "", drawioCellStyle+"fontStyle=1;align=center;",
+			fmt.Sprintf(`width="%s" height="%s"`, num(keyW), num(layout.RowHeight)))
+		writeDrawioCell(out, rowID+"-n", rowID, row.Name, columnComment, nameStyle,
+			fmt.Sprintf(`x="%s" width="%s" height="%s"`, num(keyW), num(nameW), num(layout.RowHeight)))
+		if types {
+			writeDrawioCell(out, rowID+"-t", rowID, row.Type, "", drawioCellStyle+"align=left;spacingLeft=4;fontColor=#64748b;",
+				fmt.Sprintf(`x="%s" width="%s" height="%s"`, num(keyW+nameW), num(w-keyW-nameW), num(layout.RowHeight)))
+		}
+		y += layout.RowHeight
+	}
+
+	if b.Collapsed {
+		rowID := id + "-more"
+		writeDrawioCell(out, rowID, id, "", "", drawioRowStyle,
+			fmt.Sprintf(`y="%s" width="%s" height="%s"`, num(y), num(w), num(layout.RowHeight)))
+		writeDrawioCell(out, rowID+"-n", rowID, "...", "Only key columns were extracted for this table.",
+			drawioCellStyle+"align=left;spacingLeft=4;fontStyle=2;fontColor=#64748b;",
+			fmt.Sprintf(`width="%s" height="%s"`, num(w), num(layout.RowHeight)))
+	}
+}
+
+// writeDrawioCell writes a vertex. Cells with a tooltip are wrapped in a
+// UserObject, which is how draw.io stores custom properties.
+func writeDrawioCell(out *writer, id, parent, value, tooltip, style, geometry string) {
+	indent := "        "
+	if tooltip != "" {
+		out.printf(`%s<UserObject id="%s" label="%s" tooltip="%s">`+"\n", indent, id, xmlEscape(value), strings.ReplaceAll(xmlEscape(tooltip), "\n", "&#10;"))
+		out.printf(`%s  <mxCell style="%s" vertex="1" parent="%s">`+"\n", indent, xmlEscape(style), parent)
+		out.printf(`%s    <mxGeometry %s as="geometry"/>`+"\n", indent, geometry)
+		out.printf("%s  </mxCell>\n", indent)
+		out.printf("%s</UserObject>\n", indent)
+		return
+	}
+	out.printf(`%s<mxCell id="%s" value="%s" style="%s" vertex="1" parent="%s">`+"\n", indent, id, xmlEscape(value), xmlEscape(style), parent)
+	out.printf(`%s  <mxGeometry %s as="geometry"/>`+"\n", indent, geometry)
+	out.printf("%s</mxCell>\n", indent)
+}
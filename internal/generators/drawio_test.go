@@ -0,0 +1,56 @@
+package generators
+
+import (
+	"dbv/pkg/generator"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDrawio(t *testing.T) {
+	out := generate(t, "drawio", nil, refSchema())
+
+	// The file is well-formed XML.
+	decoder := xml.NewDecoder(strings.NewReader(out))
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if err != io.EOF {
+				t.Fatalf("invalid XML: %v", err)
+			}
+			break
+		}
+	}
+
+	for _, want := range []string{
+		`<mxfile host="dbv" modified="2024-01-02T03:04:05Z"`,
+		`<mxCell id="t1" value="users" style="shape=table;`,
+		`<UserObject id="t3" label="orders" tooltip="Placed orders">`,
+		`<mxCell id="t3-r2-n" value="user_id"`,
+		// An edge per column pair, between the rows.
+		`edge="1" parent="1" source="t3-r2" target="t1-r1"`,
+		`edge="1" parent="1" source="t3-r3" target="t1-r1"`,
+		`edge="1" parent="1" source="t3-r4" target="t2-r1"`,
+		`edge="1" parent="1" source="t3-r5" target="t2-r2"`,
+		"startArrow=ERzeroToMany;",
+		"startArrow=ERoneToMany;",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q", want)
+		}
+	}
+	if n := strings.Count(out, `edge="1"`); n != 4 {
+		t.Errorf("output has %d edges, want 4", n)
+	}
+}
+
+func TestDrawioWithoutColumns(t *testing.T) {
+	out := generate(t, "drawio", generator.Options{"columns": "false"}, refSchema())
+	// Without rows, edges join the tables, once per constraint.
+	if n := strings.Count(out, `edge="1" parent="1" source="t3" target="t1"`); n != 2 {
+		t.Errorf("output has %d edges from orders to users, want 2", n)
+	}
+	if n := strings.Count(out, `edge="1" parent="1" source="t3" target="t2"`); n != 1 {
+		t.Errorf("output has %d edges from orders to accounts, want 1", n)
+	}
+}
@@ -0,0 +1,93 @@
+package generators
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGEXF(t *testing.T) {
+	out := generate(t, "gexf", nil, graphSchema())
+
+	type attvalue struct {
+		For   string `xml:"for,attr"`
+		Value string `xml:"value,attr"`
+	}
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Meta    struct {
+			LastModified string `xml:"lastmodifieddate,attr"`
+		} `xml:"meta"`
+		Graph struct {
+			DefaultEdgeType string `xml:"defaultedgetype,attr"`
+			Attributes      []struct {
+				Class     string `xml:"class,attr"`
+				Attribute []struct {
+					ID string `xml:"id,attr"`
+				} `xml:"attribute"`
+			} `xml:"attributes"`
+			Nodes []struct {
+				ID     string     `xml:"id,attr"`
+				Label  string     `xml:"label,attr"`
+				Values []attvalue `xml:"attvalues>attvalue"`
+			} `xml:"nodes>node"`
+			Edges []struct {
+				Source string     `xml:"source,attr"`
+				Target string     `xml:"target,attr"`
+				Label  string     `xml:"label,attr"`
+				Values []attvalue `xml:"attvalues>attvalue"`
+			} `xml:"edges>edge"`
+		} `xml:"graph"`
+	}
+	decodeXML(t, out, &doc)
+
+	if doc.Version != "1.2" || doc.Meta.LastModified != "2024-01-02" || doc.Graph.DefaultEdgeType != "directed" {
+		t.Errorf("header = %s, %s, %s", doc.Version, doc.Meta.LastModified, doc.Graph.DefaultEdgeType)
+	}
+
+	declared := make(map[string]map[string]bool)
+	for _, attributes := range doc.Graph.Attributes {
+		declared[attributes.Class] = make(map[string]bool)
+		for _, a := range attributes.Attribute {
+			declared[attributes.Class][a.ID] = true
+		}
+	}
+	// The label is the label attribute of the node, not an attvalue.
+	if declared["node"]["label"] {
+		t.Error("label is declared as a node attribute")
+	}
+
+	labels := make(map[string]string)
+	for _, node := range doc.Graph.Nodes {
+		labels[node.ID] = node.Label
+		values := make(map[string]string)
+		for _, v := range node.Values {
+			if !declared["node"][v.For] {
+				t.Errorf("node %s uses undeclared attribute %s", node.ID, v.For)
+			}
+			values[v.For] = v.Value
+		}
+		if node.Label == "orders" && (values["row_estimate"] != "1200" || values["comment"] != `Orders & "returns" <archived>`) {
+			t.Errorf("orders values = %v", values)
+		}
+		if node.Label == "big_orders" && values["kind"] != "view" {
+			t.Errorf("big_orders values = %v", values)
+		}
+	}
+	if len(labels) != 4 {
+		t.Errorf("graph has %d nodes, want 4", len(labels))
+	}
+
+	var edges []string
+	for _, edge := range doc.Graph.Edges {
+		for _, v := range edge.Values {
+			if !declared["edge"][v.For] {
+				t.Errorf("edge uses undeclared attribute %s", v.For)
+			}
+		}
+		edges = append(edges, labels[edge.Source]+" -> "+labels[edge.Target]+" "+edge.Label)
+	}
+	want := "orders -> users fk_orders_user; orders -> users fk_orders_user; orders -> accounts fk_orders_account_id"
+	if got := strings.Join(edges, "; "); got != want {
+		t.Errorf("edges = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,161 @@
+package generators
+
+import (
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	generator.Register("graphml", newGraphML)
+}
+
+// graphMLGenerator writes the schema as a directed GraphML graph for yEd,
+// NetworkX and other graph tools: tables and views are nodes and foreign keys
+// edges from the referencing to the referenced table, each with attributes.
+type graphMLGenerator struct{}
+
+func newGraphML(opts generator.Options) (generator.Generator, error) {
+	return &graphMLGenerator{}, nil
+}
+
+func (g *graphMLGenerator) Name() string                { return "graphml" }
+func (g *graphMLGenerator) Description() string         { return "GraphML graph" }
+func (g *graphMLGenerator) Extension() string           { return ".graphml" }
+func (g *graphMLGenerator) Options() []generator.Option { return nil }
+
+// graphAttribute describes an attribute of the nodes or edges of a graph.
+// Type is the GraphML type.
+type graphAttribute struct {
+	Name string
+	Type string
+}
+
+var (
+	graphNodeAttributes = []graphAttribute{
+		{"label", "string"},
+		{"kind", "string"},
+		{"schema", "string"},
+		{"name", "string"},
+		{"columns", "int"},
+		{"row_estimate", "long"},
+		{"comment", "string"},
+	}
+	graphEdgeAttributes = []graphAttribute{
+		{"name", "string"},
+		{"columns", "string"},
+		{"referenced_columns", "string"},
+		{"on_delete", "string"},
+		{"on_update", "string"},
+	}
+)
+
+// graphElement is a node or an edge. Values holds its attributes by name;
+// attributes that are unknown, such as a missing row estimate, are absent.
+type graphElement struct {
+	ID     string
+	Source string
+	Target string
+	Values map[string]string
+}
+
+// graphElements returns the nodes and edges of s. A composite foreign key
+// is a single edge, and edges to tables outside s are left out.
+func graphElements(s *schema.Schema) (nodes, edges []graphElement) {
+	qualify := s.Qualifier()
+	ids := make(map[[2]string]string)
+
+	node := func(schemaName, name, kind string, columns int, comment string) graphElement {
+		id := fmt.Sprintf("n%d", len(nodes))
+		ids[[2]string{schemaName, name}] = id
+		values := map[string]string{
+			"label":   qualify(schemaName, name),
+			"kind":    kind,
+			"schema":  schemaName,
+			"name":    name,
+			"columns": strconv.Itoa(columns),
+		}
+		if comment != "" {
+			values["comment"] = comment
+		}
+		return graphElement{ID: id, Values: values}
+	}
+
+	for _, table := range s.Tables {
+		n := node(table.Schema, table.Name, "table", len(table.Columns), table.Comment)
+		if table.RowEstimate != nil {
+			n.Values["row_estimate"] = strconv.FormatInt(*table.RowEstimate, 10)
+		}
+		nodes = append(nodes, n)
+	}
+	for _, view := range s.Views {
+		nodes = append(nodes, node(view.Schema, view.Name, "view", len(view.Columns), view.Comment))
+	}
+
+	for _, ref := range dbmlRefs(s) {
+		fk := ref.fk
+		source, okSource := ids[[2]string{fk.Schema, fk.Table}]
+		target, okTarget := ids[[2]string{fk.ReferencedSchema, fk.ReferencedTable}]
+		if !okSource || !okTarget {
+			continue
+		}
+
+		values := map[string]string{
+			"columns":            strings.Join(ref.columns, ", "),
+			"referenced_columns": strings.Join(ref.targets, ", "),
+		}
+		for name, value := range map[string]string{"name": fk.Name, "on_delete": fk.OnDelete, "on_update": fk.OnUpdate} {
+			if value != "" {
+				values[name] = value
+			}
+		}
+		edges = append(edges, graphElement{ID: fmt.Sprintf("e%d", len(edges)), Source: source, Target: target, Values: values})
+	}
+
+	return nodes, edges
+}
+
+func (g *graphMLGenerator) Generate(w io.Writer, s *schema.Schema) error {
+	out := newWriter(w)
+	nodes, edges := graphElements(s)
+
+	out.print(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	out.print(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"` +
+		` xsi:schemaLocation="http://graphml.graphdrawing.org/xmlns http://graphml.graphdrawing.org/xmlns/1.0/graphml.xsd">` + "\n")
+
+	// Node and edge attributes share a name space of key ids, so edge
+	// attributes are prefixed.
+	for _, a := range graphNodeAttributes {
+		out.printf(`  <key id="%s" for="node" attr.name="%s" attr.type="%s"/>`+"\n", a.Name, a.Name, a.Type)
+	}
+	for _, a := range graphEdgeAttributes {
+		out.printf(`  <key id="e_%s" for="edge" attr.name="%s" attr.type="%s"/>`+"\n", a.Name, a.Name, a.Type)
+	}
+
+	out.printf(`  <graph id="%s" edgedefault="directed">`+"\n", xmlEscape(s.Database))
+	for _, n := range nodes {
+		out.printf(`    <node id="%s">`+"\n", n.ID)
+		writeGraphMLData(out, "", graphNodeAttributes, n.Values)
+		out.print("    </node>\n")
+	}
+	for _, e := range edges {
+		out.printf(`    <edge id="%s" source="%s" target="%s">`+"\n", e.ID, e.Source, e.Target)
+		writeGraphMLData(out, "e_", graphEdgeAttributes, e.Values)
+		out.print("    </edge>\n")
+	}
+	out.print("  </graph>\n")
+	out.print("</graphml>\n")
+
+	return out.flush()
+}
+
+func writeGraphMLData(out *writer, prefix string, attributes []graphAttribute, values map[string]string) {
+	for _, a := range attributes {
+		if value, ok := values[a.Name]; ok {
+			out.printf(`      <data key="%s%s">%s</data>`+"\n", prefix, a.Name, xmlEscape(value))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package generators
+
+import (
+	"dbv/pkg/schema"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// decodeXML unmarshals out into v, failing the test when it is not
+// well-formed.
+func decodeXML(t *testing.T, out string, v any) {
+	t.Helper()
+	if err := xml.Unmarshal([]byte(out), v); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, out)
+	}
+}
+
+// graphSchema is refSchema with a view, a row estimate and markup in a
+// comment.
+func graphSchema() *schema.Schema {
+	s := refSchema()
+	rows := int64(1200)
+	s.Tables[2].RowEstimate = &rows
+	s.Tables[2].Comment = `Orders & "returns" <archived>`
+	s.Views = append(s.Views, schema.View{Name: "big_orders", Schema: "main", Columns: []schema.Column{{Name: "id", Type: "INTEGER"}}})
+	return s
+}
+
+func TestGraphML(t *testing.T) {
+	out := generate(t, "graphml", nil, graphSchema())
+
+	var doc struct {
+		Keys []struct {
+			ID  string `xml:"id,attr"`
+			For string `xml:"for,attr"`
+		} `xml:"key"`
+		Graph struct {
+			EdgeDefault string `xml:"edgedefault,attr"`
+			Nodes       []struct {
+				ID   string `xml:"id,attr"`
+				Data []struct {
+					Key   string `xml:"key,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Target string `xml:"target,attr"`
+				Data   []struct {
+					Key   string `xml:"key,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	decodeXML(t, out, &doc)
+
+	keys := make(map[string]bool)
+	for _, key := range doc.Keys {
+		keys[key.ID] = true
+	}
+	nodes := make(map[string]map[string]string)
+	for _, node := range doc.Graph.Nodes {
+		values := make(map[string]string)
+		for _, data := range node.Data {
+			if !keys[data.Key] {
+				t.Errorf("node %s uses undeclared key %s", node.ID, data.Key)
+			}
+			values[data.Key] = data.Value
+		}
+		nodes[node.ID] = values
+	}
+
+	if doc.Graph.EdgeDefault != "directed" || len(nodes) != 4 {
+		t.Fatalf("graph has %d nodes (edgedefault %q), want 4 directed", len(nodes), doc.Graph.EdgeDefault)
+	}
+	orders := nodes["n2"]
+	if orders["label"] != "orders" || orders["row_estimate"] != "1200" || orders["comment"] != `Orders & "returns" <archived>` {
+		t.Errorf("orders node = %v", orders)
+	}
+	if nodes["n3"]["kind"] != "view" {
+		t.Errorf("view node = %v", nodes["n3"])
+	}
+	if _, ok := nodes["n0"]["row_estimate"]; ok {
+		t.Error("users has a row estimate it does not know")
+	}
+
+	// A composite foreign key is a single edge.
+	var edges []string
+	for _, edge := range doc.Graph.Edges {
+		if nodes[edge.Source] == nil || nodes[edge.Target] == nil {
+			t.Errorf("edge %s -> %s joins unknown nodes", edge.Source, edge.Target)
+		}
+		values := make(map[string]string)
+		for _, data := range edge.Data {
+			if !keys[data.Key] {
+				t.Errorf("edge uses undeclared key %s", data.Key)
+			}
+			values[data.Key] = data.Value
+		}
+		edges = append(edges, nodes[edge.Source]["name"]+"."+values["e_columns"]+" -> "+
+			nodes[edge.Target]["name"]+"."+values["e_referenced_columns"])
+	}
+	want := "orders.user_id -> users.id; orders.created_by -> users.id; " +
+		"orders.account_id, account_region -> accounts.id, region"
+	if got := strings.Join(edges, "; "); got != want {
+		t.Errorf("edges = %s, want %s", got, want)
+	}
+}
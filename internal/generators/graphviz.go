@@ -1,21 +1,42 @@
 package generators
 
 import (
-	"dbv/internal/schema"
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
 	"fmt"
+	"io"
 	"strings"
 )
 
-func GenerateGraphviz(s *schema.Schema) string {
-    var builder strings.Builder
+func init() {
+    generator.Register("graphviz", newGraphviz)
+}
+
+// graphvizGenerator renders a Graphviz digraph of record nodes.
+type graphvizGenerator struct{}
+
+func newGraphviz(generator.Options) (generator.Generator, error) {
+    return &graphvizGenerator{}, nil
+}
 
-    builder.WriteString("digraph schema {\n")
-    builder.WriteString("  rankdir=TB;\n")
-    builder.WriteString("  node [shape=record, style=filled, fillcolor=lightblue];\n")
-    builder.WriteString("  edge [color=gray];\n\n")
+func (g *graphvizGenerator) Name() string        { return "graphviz" }
+func (g *graphvizGenerator) Description() string { return "Graphviz" }
+func (g *graphvizGenerator) Extension() string   { return ".dot" }
+
+func (g *graphvizGenerator) Options() []generator.Option { return nil }
+
+func (g *graphvizGenerator) Generate(w io.Writer, s *schema.Schema) error {
+    out := newWriter(w)
+    qualify := s.Qualifier()
+
+    out.print("digraph schema {\n")
+    out.print("  rankdir=TB;\n")
+    out.print("  node [shape=record, style=filled, fillcolor=lightblue];\n")
+    out.print("  edge [color=gray];\n\n")
 
     for _, table := range s.Tables {
-        builder.WriteString(fmt.Sprintf("  %s [label=\"{%s|", cleanNodeName(table.Name), table.Name))
+        name := qualify(table.Schema, table.Name)
+        out.printf("  %s [label=\"{%s|", cleanNodeName(name), name)
         
         var fields []string
         for _, col := range table.Columns {
@@ -29,13 +50,18 @@ func GenerateGraphviz(s *schema.Schema) string {
             fields = append(fields, field)
         }
         
-        builder.WriteString(strings.Join(fields, "\\l"))
-        builder.WriteString("\\l}\"];\n")
+        out.print(strings.Join(fields, "\\l"))
+        if table.Collapsed {
+            out.print("\\l…\\l}\", fillcolor=lightgrey];\n")
+        } else {
+            out.print("\\l}\"];\n")
+        }
     }
 
     // Generate views
     for _, view := range s.Views {
-        builder.WriteString(fmt.Sprintf("  %s [label=\"{%s (VIEW)|", cleanNodeName(view.Name), view.Name))
+        name := qualify(view.Schema, view.Name)
+        out.printf("  %s [label=\"{%s (VIEW)|", cleanNodeName(name), name)
         
         var fields []string
         for _, col := range view.Columns {
@@ -43,22 +69,22 @@ func GenerateGraphviz(s *schema.Schema) string {
             fields = append(fields, field)
         }
         
-        builder.WriteString(strings.Join(fields, "\\l"))
-        builder.WriteString("\\l}\", fillcolor=lightgreen];\n")
+        out.print(strings.Join(fields, "\\l"))
+        out.print("\\l}\", fillcolor=lightgreen];\n")
     }
 
-    builder.WriteString("\n")
+    out.print("\n")
 
     for _, fk := range s.ForeignKeys {
-        builder.WriteString(fmt.Sprintf("  %s -> %s [label=\"%s\"];\n", 
-            cleanNodeName(fk.ReferencedTable), 
-            cleanNodeName(fk.Table), 
-            fk.Column))
+        out.printf("  %s -> %s [label=\"%s\"];\n", 
+            cleanNodeName(qualify(fk.ReferencedSchema, fk.ReferencedTable)), 
+            cleanNodeName(qualify(fk.Schema, fk.Table)), 
+            fk.Column)
     }
 
-    builder.WriteString("}\n")
+    out.print("}\n")
 
-    return builder.String()
+    return out.flush()
 }
 
 func formatGraphvizType(col schema.Column) string {
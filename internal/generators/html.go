@@ -0,0 +1,133 @@
+package generators
+
+import (
+	"bufio"
+	"dbv/internal/layout"
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
+	_ "embed"
+	"html/template"
+	"io"
+	"strings"
+)
+
+func init() {
+	generator.Register("html", newHTML)
+}
+
+//go:embed html.tmpl
+var htmlSource string
+
+var htmlTemplate = template.Must(template.New("html").Parse(htmlSource))
+
+// htmlGenerator writes a single offline page that draws the laid out diagram
+// in the browser, with pan and zoom, search and a detail panel per table. The
+// schema and the layout are embedded as JSON; the page loads nothing else.
+type htmlGenerator struct {
+	opts layout.Options
+}
+
+func newHTML(opts generator.Options) (generator.Generator, error) {
+	lopts, err := layoutOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &htmlGenerator{opts: lopts}, nil
+}
+
+func (g *htmlGenerator) Name() string                { return "html" }
+func (g *htmlGenerator) Description() string         { return "Interactive HTML viewer" }
+func (g *htmlGenerator) Extension() string           { return ".html" }
+func (g *htmlGenerator) Options() []generator.Option { return layoutOptionList }
+
+// htmlData is the JSON embedded in the page.
+type htmlData struct {
+	Schema *schema.Schema `json:"schema"`
+	Width  float64        `json:"width"`
+	Height float64        `json:"height"`
+	Boxes  []htmlBox      `json:"boxes"`
+	Edges  []htmlEdge     `json:"edges"`
+}
+
+type htmlBox struct {
+	Schema    string    `json:"schema"`
+	Name      string    `json:"name"`
+	Title     string    `json:"title"`
+	View      bool      `json:"view"`
+	Collapsed bool      `json:"collapsed"`
+	X         float64   `json:"x"`
+	Y         float64   `json:"y"`
+	W         float64   `json:"w"`
+	H         float64   `json:"h"`
+	Rows      []htmlRow `json:"rows"`
+}
+
+type htmlRow struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Key  string `json:"key"`
+}
+
+// htmlEdge connects the boxes at indexes From and To. Path is the SVG path of
+// the line and Marks that of the crow's-foot lines, with Circles as x, y, r.
+type htmlEdge struct {
+	From       int               `json:"from"`
+	To         int               `json:"to"`
+	ForeignKey schema.ForeignKey `json:"fk"`
+	Path       string            `json:"path"`
+	Marks      string            `json:"marks"`
+	Circles    [][3]float64      `json:"circles"`
+}
+
+func (g *htmlGenerator) Generate(w io.Writer, s *schema.Schema) error {
+	d := layout.Compute(s, g.opts)
+
+	data := htmlData{Schema: s, Width: d.Width, Height: d.Height, Boxes: []htmlBox{}, Edges: []htmlEdge{}}
+	index := make(map[[2]string]int, len(d.Boxes))
+	for i, b := range d.Boxes {
+		index[[2]string{b.Schema, b.Name}] = i
+		box := htmlBox{
+			Schema: b.Schema, Name: b.Name, Title: b.Title, View: b.View, Collapsed: b.Collapsed,
+			X: b.X, Y: b.Y, W: b.W, H: b.H, Rows: []htmlRow{},
+		}
+		for _, row := range b.Rows {
+			box.Rows = append(box.Rows, htmlRow{Name: row.Name, Type: row.Type, Key: row.Key})
+		}
+		data.Boxes = append(data.Boxes, box)
+	}
+
+	for _, e := range d.Edges {
+		fk := e.ForeignKey
+		edge := htmlEdge{
+			From:       index[[2]string{fk.Schema, fk.Table}],
+			To:         index[[2]string{fk.ReferencedSchema, fk.ReferencedTable}],
+			ForeignKey: fk,
+			Path:       svgPath(e.Points...),
+			Circles:    [][3]float64{},
+		}
+
+		n := len(e.Points)
+		marks := crowsFoot(e.Points[0], e.Points[1], true, e.Optional)
+		marks = append(marks, crowsFoot(e.Points[n-1], e.Points[n-2], false, false)...)
+		var paths []string
+		for _, m := range marks {
+			if m.circle {
+				edge.Circles = append(edge.Circles, [3]float64{m.a.X, m.a.Y, m.r})
+				continue
+			}
+			paths = append(paths, svgPath(m.a, m.b))
+		}
+		edge.Marks = strings.Join(paths, " ")
+
+		data.Edges = append(data.Edges, edge)
+	}
+
+	out := bufio.NewWriter(w)
+	if err := htmlTemplate.Execute(out, struct {
+		Title string
+		Data  htmlData
+	}{s.Database + " schema", data}); err != nil {
+		return err
+	}
+	return out.Flush()
+}
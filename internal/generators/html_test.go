@@ -0,0 +1,52 @@
+package generators
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	s := refSchema()
+	s.Tables[2].Comment = "</script><script>alert(1)</script>"
+	out := generate(t, "html", nil, s)
+
+	if strings.Contains(out, "<script>alert(1)") {
+		t.Error("a table comment closes the script element")
+	}
+	for _, external := range []string{"<link", "src=", "https://"} {
+		if strings.Contains(out, external) {
+			t.Errorf("the page loads something: it contains %q", external)
+		}
+	}
+	if !strings.Contains(out, "<title>sqlite schema</title>") {
+		t.Error("output lacks the title")
+	}
+
+	// The embedded data is the schema and its layout.
+	_, rest, ok := strings.Cut(out, "const data = ")
+	if !ok {
+		t.Fatal("output lacks the embedded data")
+	}
+	var data htmlData
+	if err := json.NewDecoder(strings.NewReader(rest)).Decode(&data); err != nil {
+		t.Fatalf("embedded data is not JSON: %v", err)
+	}
+	if len(data.Schema.Tables) != 3 || data.Schema.Tables[2].Comment != s.Tables[2].Comment {
+		t.Errorf("embedded schema = %+v", data.Schema.Tables)
+	}
+	if len(data.Boxes) != 3 || data.Width <= 0 || data.Height <= 0 {
+		t.Errorf("layout has %d boxes in %gx%g, want 3", len(data.Boxes), data.Width, data.Height)
+	}
+	for _, edge := range data.Edges {
+		if data.Boxes[edge.From].Name != "orders" || edge.Path == "" {
+			t.Errorf("edge %+v does not start at orders", edge)
+		}
+		if to := data.Boxes[edge.To].Name; to != edge.ForeignKey.ReferencedTable {
+			t.Errorf("edge of %s ends at %s", edge.ForeignKey.Name, to)
+		}
+	}
+	if len(data.Edges) != 4 {
+		t.Errorf("layout has %d edges, want one per foreign key column", len(data.Edges))
+	}
+}
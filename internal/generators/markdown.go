@@ -0,0 +1,64 @@
+package generators
+
+import (
+	"bufio"
+	"bytes"
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
+	"io"
+	"strings"
+)
+
+func init() {
+	generator.Register("markdown", newMarkdown)
+}
+
+// markdownGenerator renders a data dictionary as one Markdown document: a
+// section per table and view with its columns and relationships, optionally
+// preceded by the Mermaid diagram.
+type markdownGenerator struct {
+	diagram bool
+}
+
+func newMarkdown(opts generator.Options) (generator.Generator, error) {
+	diagram, err := opts.Bool("diagram", true)
+	if err != nil {
+		return nil, err
+	}
+	return &markdownGenerator{diagram: diagram}, nil
+}
+
+func (g *markdownGenerator) Name() string        { return "markdown" }
+func (g *markdownGenerator) Description() string { return "Markdown data dictionary" }
+func (g *markdownGenerator) Extension() string   { return ".md" }
+
+func (g *markdownGenerator) Options() []generator.Option {
+	return []generator.Option{
+		{Name: "diagram", Description: "Include the Mermaid ER diagram", Default: "true"},
+	}
+}
+
+func (g *markdownGenerator) Generate(w io.Writer, s *schema.Schema) error {
+	// Sections are linked through explicit anchors, which survive the
+	// heading slug rules of the different Markdown renderers.
+	site := newDocsSite(s, s.Database+" data dictionary", "####", func(dir, name string) (string, string) {
+		id := strings.TrimSuffix(dir, "s") + "-" + name
+		return id, "#" + id
+	})
+
+	if g.diagram && len(s.Tables)+len(s.Views) > 0 {
+		var diagram bytes.Buffer
+		out := newWriter(&diagram)
+		writeMermaidER(out, s)
+		if err := out.flush(); err != nil {
+			return err
+		}
+		site.Mermaid = diagram.String()
+	}
+
+	out := bufio.NewWriter(w)
+	if err := docsMarkdownTemplate.ExecuteTemplate(out, "dictionary", site); err != nil {
+		return err
+	}
+	return out.Flush()
+}
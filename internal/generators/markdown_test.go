@@ -0,0 +1,47 @@
+package generators
+
+import (
+	"dbv/pkg/generator"
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	s := refSchema()
+	s.Tables[2].Columns[1].Comment = "who | placed it"
+	out := generate(t, "markdown", nil, s)
+
+	for _, want := range []string{
+		"# sqlite data dictionary\n",
+		"Tables: 3 · Views: 0 · Relationships: 4 · Generated 2024-01-02 03:04 UTC\n",
+		"```mermaid\nerDiagram\n",
+		"- [orders](#table-orders): Placed orders\n",
+		"<a id=\"table-orders\"></a>\n### orders\n\nPlaced orders\n",
+		"| `user_id` | `integer` | yes |  | FK → [users](#table-users).id | who \\| placed it |\n",
+		"Primary key: `id, region`\n",
+		"| [orders](#table-orders) | `created_by` | `id` |  | CASCADE | fk_orders_user |\n",
+		"| `account_region` | [accounts](#table-accounts).region |  | CASCADE | fk_orders_account_id |\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	// Every link points at an anchor of the document.
+	for _, part := range strings.Split(out, "](#")[1:] {
+		anchor, _, _ := strings.Cut(part, ")")
+		if !strings.Contains(out, `<a id="`+anchor+`"></a>`) {
+			t.Errorf("link to #%s has no anchor", anchor)
+		}
+	}
+}
+
+func TestMarkdownWithoutDiagram(t *testing.T) {
+	out := generate(t, "markdown", generator.Options{"diagram": "false"}, refSchema())
+	if strings.Contains(out, "mermaid") {
+		t.Errorf("output has a diagram:\n%s", out)
+	}
+	if !strings.Contains(out, "### users") {
+		t.Errorf("output lacks the users section:\n%s", out)
+	}
+}
@@ -1,19 +1,75 @@
 package generators
 
 import (
-	"dbv/internal/schema"
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
 	"fmt"
+	"io"
 	"strings"
 )
 
-func GenerateMermaid(s *schema.Schema) string {
-    var builder strings.Builder
+func init() {
+    generator.Register("mermaid", newMermaid)
+}
+
+// mermaidGenerator renders a Mermaid erDiagram, by default wrapped in a
+// Markdown document.
+type mermaidGenerator struct {
+    markdown bool
+}
+
+func newMermaid(opts generator.Options) (generator.Generator, error) {
+    markdown, err := opts.Bool("markdown", true)
+    if err != nil {
+        return nil, err
+    }
+    return &mermaidGenerator{markdown: markdown}, nil
+}
 
-    builder.WriteString("# Database Schema Diagram\n\n")
-    builder.WriteString("```mermaid\nerDiagram\n")
+func (g *mermaidGenerator) Name() string        { return "mermaid" }
+func (g *mermaidGenerator) Description() string { return "Mermaid" }
+
+func (g *mermaidGenerator) Extension() string {
+    if g.markdown {
+        return ".md"
+    }
+    return ".mmd"
+}
+
+func (g *mermaidGenerator) Options() []generator.Option {
+    return []generator.Option{
+        {Name: "markdown", Description: "Wrap the diagram in a Markdown document with a summary", Default: "true"},
+    }
+}
+
+func (g *mermaidGenerator) Generate(w io.Writer, s *schema.Schema) error {
+    out := newWriter(w)
+
+    if g.markdown {
+        out.print("# Database Schema Diagram\n\n")
+        out.print("```mermaid\n")
+    }
+    writeMermaidER(out, s)
+
+    if g.markdown {
+        out.print("```\n\n")
+        out.printf("Generated on: %s\n", s.GeneratedAt.Format("2006-01-02 15:04:05"))
+        out.printf("Total Tables: %d\n", len(s.Tables))
+        out.printf("Total Views: %d\n", len(s.Views))
+        out.printf("Total Foreign Keys: %d\n", len(s.ForeignKeys))
+    }
+
+    return out.flush()
+}
+
+// writeMermaidER writes the erDiagram of s, without Markdown fences.
+func writeMermaidER(out *writer, s *schema.Schema) {
+    qualify := s.Qualifier()
+
+    out.print("erDiagram\n")
 
     for _, table := range s.Tables {
-        builder.WriteString(fmt.Sprintf("    %s {\n", cleanTableName(table.Name)))
+        out.printf("    %s {\n", cleanTableName(qualify(table.Schema, table.Name)))
         
         for _, col := range table.Columns {
             typeStr := formatMermaidType(col)
@@ -24,39 +80,31 @@ func GenerateMermaid(s *schema.Schema) string {
                 keyStr = " NOT NULL"
             }
             
-            builder.WriteString(fmt.Sprintf("        %s %s%s\n", typeStr, col.Name, keyStr))
+            out.printf("        %s %s%s\n", typeStr, col.Name, keyStr)
         }
         
-        builder.WriteString("    }\n\n")
+        out.print("    }\n\n")
     }
 
     for _, view := range s.Views {
-        builder.WriteString(fmt.Sprintf("    %s {\n", cleanTableName(view.Name)))
+        out.printf("    %s {\n", cleanTableName(qualify(view.Schema, view.Name)))
         
         for _, col := range view.Columns {
             typeStr := formatMermaidType(col)
-            builder.WriteString(fmt.Sprintf("        %s %s\n", typeStr, col.Name))
+            out.printf("        %s %s\n", typeStr, col.Name)
         }
         
-        builder.WriteString("    }\n\n")
+        out.print("    }\n\n")
     }
 
     for _, fk := range s.ForeignKeys {
         relationship := determineRelationship(fk)
-        builder.WriteString(fmt.Sprintf("    %s %s %s : %s\n", 
-            cleanTableName(fk.ReferencedTable), 
+        out.printf("    %s %s %s : %s\n", 
+            cleanTableName(qualify(fk.ReferencedSchema, fk.ReferencedTable)), 
             relationship, 
-            cleanTableName(fk.Table), 
-            fk.Column))
+            cleanTableName(qualify(fk.Schema, fk.Table)), 
+            fk.Column)
     }
-
-    builder.WriteString("```\n\n")
-    builder.WriteString(fmt.Sprintf("Generated on: %s\n", s.GeneratedAt.Format("2006-01-02 15:04:05")))
-    builder.WriteString(fmt.Sprintf("Total Tables: %d\n", len(s.Tables)))
-    builder.WriteString(fmt.Sprintf("Total Views: %d\n", len(s.Views)))
-    builder.WriteString(fmt.Sprintf("Total Foreign Keys: %d\n", len(s.ForeignKeys)))
-
-    return builder.String()
 }
 
 func formatMermaidType(col schema.Column) string {
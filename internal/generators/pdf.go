@@ -0,0 +1,467 @@
+package generators
+
+import (
+	"dbv/internal/layout"
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	generator.Register("pdf", newPDF)
+}
+
+// Paper sizes in points, portrait.
+var paperSizes = map[string][2]float64{
+	"a5":      {419.53, 595.28},
+	"a4":      {595.28, 841.89},
+	"a3":      {841.89, 1190.55},
+	"a2":      {1190.55, 1683.78},
+	"letter":  {612, 792},
+	"legal":   {612, 1008},
+	"tabloid": {792, 1224},
+}
+
+const (
+	pdfMargin = 36.0
+	// pdfFooter is the band under the diagram area for the page label.
+	pdfFooter = 14.0
+)
+
+// pdfGenerator renders the laid out ER diagram for printing. Diagrams larger
+// than a page are tiled over several pages that overlap by a margin marked on
+// every page, preceded by an index of the tables and the pages they are on.
+type pdfGenerator struct {
+	opts        layout.Options
+	paper       string
+	orientation string
+	scale       float64
+	fit         bool
+	overlap     float64
+	index       string
+}
+
+func newPDF(opts generator.Options) (generator.Generator, error) {
+	lopts, err := layoutOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	g := &pdfGenerator{
+		opts:        lopts,
+		paper:       strings.ToLower(opts.String("paper", "a4")),
+		orientation: strings.ToLower(opts.String("orientation", "auto")),
+		index:       strings.ToLower(opts.String("index", "auto")),
+	}
+
+	if _, ok := paperSizes[g.paper]; !ok {
+		return nil, fmt.Errorf("option paper: unknown size '%s'. Valid sizes: %s", g.paper, strings.Join(paperNames(), ", "))
+	}
+	switch g.orientation {
+	case "auto", "portrait", "landscape":
+	default:
+		return nil, fmt.Errorf("option orientation: invalid value '%s'. Valid values: auto, portrait, landscape", g.orientation)
+	}
+	switch g.index {
+	case "auto", "always", "never":
+	default:
+		return nil, fmt.Errorf("option index: invalid value '%s'. Valid values: auto, always, never", g.index)
+	}
+	if g.scale, err = floatOption(opts, "scale", 1, false); err != nil {
+		return nil, err
+	}
+	if g.overlap, err = floatOption(opts, "overlap", 36, true); err != nil {
+		return nil, err
+	}
+	if g.fit, err = opts.Bool("fit", false); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// floatOption returns the named option as a finite number greater than 0, or
+// from 0 when zero is allowed, and def when it is unset.
+func floatOption(opts generator.Options, name string, def float64, zero bool) (float64, error) {
+	value := opts.String(name, "")
+	if value == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) || f < 0 || (f == 0 && !zero) {
+		kind := "positive"
+		if zero {
+			kind = "non-negative"
+		}
+		return 0, fmt.Errorf("option %s: invalid %s number '%s'", name, kind, value)
+	}
+	return f, nil
+}
+
+func paperNames() []string {
+	names := make([]string, 0, len(paperSizes))
+	for name := range paperSizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (g *pdfGenerator) Name() string        { return "pdf" }
+func (g *pdfGenerator) Description() string { return "PDF for printing" }
+func (g *pdfGenerator) Extension() string   { return ".pdf" }
+
+func (g *pdfGenerator) Options() []generator.Option {
+	return append([]generator.Option{
+		{Name: "paper", Description: "Paper size: " + strings.Join(paperNames(), ", "), Default: "a4"},
+		{Name: "orientation", Description: "auto picks the orientation that needs fewer pages, or portrait, landscape", Default: "auto"},
+		{Name: "scale", Description: "Diagram scale, 1 draws 12pt text", Default: "1"},
+		{Name: "fit", Description: "Shrink the diagram to fit a single page instead of tiling", Default: "false"},
+		{Name: "overlap", Description: "Overlap between tiled pages, in points", Default: "36"},
+		{Name: "index", Description: "Table index page: auto (when tiled), always, never", Default: "auto"},
+	}, layoutOptionList...)
+}
+
+// tiling is the division of a diagram into pages.
+type tiling struct {
+	pageW, pageH float64
+	// areaW and areaH are the printable area of a page, in points.
+	areaW, areaH float64
+	scale        float64
+	// stepX and stepY are the distances between tile origins and overlap
+	// the overlap between tiles, in diagram units.
+	stepX, stepY float64
+	overlap      float64
+	rows, cols   int
+}
+
+func (g *pdfGenerator) tile(d *layout.Diagram, landscape bool) tiling {
+	size := paperSizes[g.paper]
+	t := tiling{pageW: size[0], pageH: size[1]}
+	if landscape {
+		t.pageW, t.pageH = t.pageH, t.pageW
+	}
+	t.areaW = t.pageW - 2*pdfMargin
+	t.areaH = t.pageH - 2*pdfMargin - pdfFooter
+
+	t.scale = g.scale
+	if g.fit {
+		t.scale = math.Min(t.scale, math.Min(t.areaW/d.Width, t.areaH/d.Height))
+	}
+
+	tileW, tileH := t.areaW/t.scale, t.areaH/t.scale
+	t.overlap = math.Min(g.overlap/t.scale, math.Min(tileW, tileH)/2)
+	t.stepX, t.stepY = tileW-t.overlap, tileH-t.overlap
+	t.cols, t.rows = 1, 1
+	if d.Width > tileW {
+		t.cols = int(math.Ceil((d.Width - t.overlap) / t.stepX))
+	}
+	if d.Height > tileH {
+		t.rows = int(math.Ceil((d.Height - t.overlap) / t.stepY))
+	}
+
+	return t
+}
+
+// tileLabel names the tile in row r and column c like a map grid: A1, A2, B1.
+func tileLabel(r, c int) string {
+	row := ""
+	for n := r; ; n = n/26 - 1 {
+		row = string(rune('A'+n%26)) + row
+		if n < 26 {
+			break
+		}
+	}
+	return fmt.Sprintf("%s%d", row, c+1)
+}
+
+func (g *pdfGenerator) Generate(w io.Writer, s *schema.Schema) error {
+	d := layout.Compute(s, g.opts)
+
+	var t tiling
+	switch g.orientation {
+	case "portrait":
+		t = g.tile(d, false)
+	case "landscape":
+		t = g.tile(d, true)
+	default:
+		portrait, landscape := g.tile(d, false), g.tile(d, true)
+		pp, lp := portrait.rows*portrait.cols, landscape.rows*landscape.cols
+		t = portrait
+		switch {
+		case lp != pp:
+			if lp < pp {
+				t = landscape
+			}
+		case g.fit:
+			if landscape.scale > portrait.scale {
+				t = landscape
+			}
+		case d.Width > d.Height:
+			t = landscape
+		}
+	}
+
+	title := s.Database + " schema"
+	doc := newPDFDocument(w, t.pageW, t.pageH)
+
+	if g.index == "always" || (g.index == "auto" && t.rows*t.cols > 1) {
+		g.writeIndex(doc, d, t, s, title)
+	}
+
+	for r := 0; r < t.rows; r++ {
+		for c := 0; c < t.cols; c++ {
+			doc.addPage(g.drawTile(d, t, r, c, title))
+		}
+	}
+
+	return doc.close(title, s.GeneratedAt)
+}
+
+// drawTile draws the part of the diagram on the page of tile r, c, with the
+// overlap with neighbouring pages shaded and labelled.
+func (g *pdfGenerator) drawTile(d *layout.Diagram, t tiling, r, c int, title string) *pdfCanvas {
+	canvas := newPDFCanvas(t.pageH)
+	ox, oy := float64(c)*t.stepX, float64(r)*t.stepY
+	ov := t.overlap * t.scale
+	right, bottom := pdfMargin+t.areaW, pdfMargin+t.areaH
+
+	// Shade the strips that also appear on the neighbouring pages.
+	canvas.fillColor(0.95, 0.95, 0.95)
+	if c > 0 {
+		canvas.rect(pdfMargin, pdfMargin, ov, t.areaH, true, false)
+	}
+	if c < t.cols-1 {
+		canvas.rect(right-ov, pdfMargin, ov, t.areaH, true, false)
+	}
+	if r > 0 {
+		canvas.rect(pdfMargin, pdfMargin, t.areaW, ov, true, false)
+	}
+	if r < t.rows-1 {
+		canvas.rect(pdfMargin, bottom-ov, t.areaW, ov, true, false)
+	}
+
+	canvas.save()
+	canvas.clip(pdfMargin, pdfMargin, t.areaW, t.areaH)
+	canvas.transform(t.scale, pdfMargin-ox*t.scale, pdfMargin-oy*t.scale)
+	view := [4]float64{ox, oy, ox + t.areaW/t.scale, oy + t.areaH/t.scale}
+	drawPDFDiagram(canvas, d, view)
+	canvas.restore()
+
+	// Mark where the overlap starts and name the neighbouring pages.
+	canvas.strokeColor(0.6, 0.6, 0.6)
+	canvas.lineWidth(0.5)
+	canvas.dash(3, 3)
+	canvas.fillColor(0.4, 0.4, 0.4)
+	midX, midY := pdfMargin+t.areaW/2, pdfMargin+t.areaH/2
+	if c > 0 {
+		canvas.polyline([2]float64{pdfMargin + ov, pdfMargin}, [2]float64{pdfMargin + ov, bottom})
+		canvas.text(false, 8, 4, midY, "< "+tileLabel(r, c-1))
+	}
+	if c < t.cols-1 {
+		canvas.polyline([2]float64{right - ov, pdfMargin}, [2]float64{right - ov, bottom})
+		canvas.text(false, 8, right+4, midY, tileLabel(r, c+1)+" >")
+	}
+	if r > 0 {
+		canvas.polyline([2]float64{pdfMargin, pdfMargin + ov}, [2]float64{right, pdfMargin + ov})
+		label := "^ " + tileLabel(r-1, c)
+		canvas.text(false, 8, midX-pdfTextWidth(label, 8)/2, pdfMargin-8, label)
+	}
+	if r < t.rows-1 {
+		canvas.polyline([2]float64{pdfMargin, bottom - ov}, [2]float64{right, bottom - ov})
+		label := "v " + tileLabel(r+1, c)
+		canvas.text(false, 8, midX-pdfTextWidth(label, 8)/2, bottom+pdfFooter-4, label)
+	}
+	canvas.dash()
+
+	footer := title
+	if t.rows*t.cols > 1 {
+		footer += fmt.Sprintf(" - page %s (row %d of %d, column %d of %d)", tileLabel(r, c), r+1, t.rows, c+1, t.cols)
+	}
+	canvas.text(false, 8, pdfMargin, t.pageH-pdfMargin/2, footer)
+
+	return canvas
+}
+
+// drawPDFDiagram draws the boxes and edges of d that intersect view, given
+// as left, top, right and bottom in diagram units.
+func drawPDFDiagram(canvas *pdfCanvas, d *layout.Diagram, view [4]float64) {
+	visible := func(x0, y0, x1, y1 float64) bool {
+		return x1 >= view[0] && x0 <= view[2] && y1 >= view[1] && y0 <= view[3]
+	}
+
+	canvas.strokeColor(0.39, 0.45, 0.55)
+	canvas.lineWidth(1.2)
+	for _, e := range d.Edges {
+		x0, y0, x1, y1 := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+		points := make([][2]float64, len(e.Points))
+		for i, p := range e.Points {
+			points[i] = [2]float64{p.X, p.Y}
+			x0, y0, x1, y1 = math.Min(x0, p.X), math.Min(y0, p.Y), math.Max(x1, p.X), math.Max(y1, p.Y)
+		}
+		if !visible(x0-20, y0-20, x1+20, y1+20) {
+			continue
+		}
+		canvas.polyline(points...)
+
+		n := len(e.Points)
+		marks := crowsFoot(e.Points[0], e.Points[1], true, e.Optional)
+		marks = append(marks, crowsFoot(e.Points[n-1], e.Points[n-2], false, false)...)
+		canvas.fillColor(1, 1, 1)
+		for _, m := range marks {
+			if m.circle {
+				canvas.circle(m.a.X, m.a.Y, m.r, true, true)
+			} else {
+				canvas.polyline([2]float64{m.a.X, m.a.Y}, [2]float64{m.b.X, m.b.Y})
+			}
+		}
+	}
+
+	canvas.lineWidth(1)
+	for _, b := range d.Boxes {
+		if !visible(b.X, b.Y, b.X+b.W, b.Y+b.H) {
+			continue
+		}
+		drawPDFBox(canvas, b)
+	}
+}
+
+func drawPDFBox(canvas *pdfCanvas, b layout.Box) {
+	canvas.strokeColor(0.39, 0.45, 0.55)
+	canvas.fillColor(1, 1, 1)
+	canvas.rect(b.X, b.Y, b.W, b.H, true, true)
+	switch {
+	case b.View:
+		canvas.fillColor(0.86, 0.99, 0.91)
+	case b.Collapsed:
+		canvas.fillColor(0.9, 0.91, 0.92)
+	default:
+		canvas.fillColor(0.86, 0.92, 1)
+	}
+	canvas.rect(b.X, b.Y, b.W, layout.HeaderSize, true, true)
+
+	canvas.fillColor(0.12, 0.16, 0.22)
+	canvas.text(true, layout.FontSize, b.X+layout.Padding, b.Y+layout.HeaderSize-7, b.Title)
+
+	nameX := b.X + layout.Padding + layout.TextWidth("PK ")
+	for i, row := range b.Rows {
+		y := b.Y + layout.HeaderSize + float64(i+1)*layout.RowHeight - 5
+		if row.Key != "" {
+			canvas.fillColor(0.71, 0.33, 0.04)
+			canvas.text(false, 10, b.X+layout.Padding, y, row.Key)
+		}
+		canvas.fillColor(0.12, 0.16, 0.22)
+		canvas.text(false, layout.FontSize, nameX, y, row.Name)
+		if row.Type != "" {
+			canvas.fillColor(0.42, 0.45, 0.5)
+			canvas.text(false, layout.FontSize, b.X+b.W-layout.Padding-layout.TextWidth(row.Type), y, row.Type)
+		}
+	}
+	if b.Collapsed {
+		y := b.Y + layout.HeaderSize + float64(len(b.Rows)+1)*layout.RowHeight - 5
+		canvas.fillColor(0.42, 0.45, 0.5)
+		canvas.text(false, layout.FontSize, nameX, y, "…")
+	}
+}
+
+// pdfTextWidth is the width of s in the monospaced font at size.
+func pdfTextWidth(s string, size float64) float64 {
+	return float64(len([]rune(s))) * layout.CharWidth * size
+}
+
+// writeIndex adds the index pages: an overview of the diagram with the page
+// grid, followed by every table and view with the page its header is on.
+func (g *pdfGenerator) writeIndex(doc *pdfDocument, d *layout.Diagram, t tiling, s *schema.Schema, title string) {
+	const (
+		fontSize = 9.0
+		leading  = 12.0
+	)
+
+	type entry struct{ name, page string }
+	entries := make([]entry, 0, len(d.Boxes))
+	for _, b := range d.Boxes {
+		c := min(t.cols-1, int(b.X/t.stepX))
+		r := min(t.rows-1, int(b.Y/t.stepY))
+		name := b.Title
+		if b.View {
+			name += " (view)"
+		}
+		entries = append(entries, entry{name, tileLabel(r, c)})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return strings.ToLower(entries[i].name) < strings.ToLower(entries[j].name) })
+
+	width := 0.0
+	for _, e := range entries {
+		width = math.Max(width, pdfTextWidth(e.name+e.page, fontSize)+4*layout.CharWidth*fontSize)
+	}
+	columns := max(1, int((t.areaW+24)/(width+24)))
+	columnW := (t.areaW - float64(columns-1)*24) / float64(columns)
+
+	canvas := newPDFCanvas(t.pageH)
+	canvas.fillColor(0.12, 0.16, 0.22)
+	canvas.text(true, 16, pdfMargin, pdfMargin+16, title)
+	canvas.text(false, fontSize, pdfMargin, pdfMargin+32, fmt.Sprintf("Generated %s - %d tables, %d views, %d relationships - %d page(s): rows A-%s, columns 1-%d",
+		s.GeneratedAt.Format("2006-01-02 15:04"), len(s.Tables), len(s.Views), len(s.ForeignKeys),
+		t.rows*t.cols, strings.TrimRight(tileLabel(t.rows-1, 0), "1"), t.cols))
+
+	// Overview: the whole diagram shrunk into the top of the page, with the
+	// page grid over it.
+	top := pdfMargin + 48
+	overviewH := t.areaH * 0.4
+	k := math.Min(t.areaW/d.Width, overviewH/d.Height)
+	canvas.save()
+	canvas.transform(k, pdfMargin, top)
+	canvas.lineWidth(1 / k)
+	canvas.strokeColor(0.39, 0.45, 0.55)
+	for _, e := range d.Edges {
+		points := make([][2]float64, len(e.Points))
+		for i, p := range e.Points {
+			points[i] = [2]float64{p.X, p.Y}
+		}
+		canvas.polyline(points...)
+	}
+	canvas.fillColor(0.86, 0.92, 1)
+	for _, b := range d.Boxes {
+		canvas.rect(b.X, b.Y, b.W, b.H, true, true)
+	}
+	canvas.strokeColor(0.8, 0.2, 0.2)
+	canvas.dash(4/k, 3/k)
+	canvas.fillColor(0.8, 0.2, 0.2)
+	for r := 0; r < t.rows; r++ {
+		for c := 0; c < t.cols; c++ {
+			x, y := float64(c)*t.stepX, float64(r)*t.stepY
+			w, h := math.Min(t.stepX+t.overlap, d.Width-x), math.Min(t.stepY+t.overlap, d.Height-y)
+			canvas.rect(x, y, w, h, false, true)
+			canvas.text(true, 10/k, x+4/k, y+12/k, tileLabel(r, c))
+		}
+	}
+	canvas.dash()
+	canvas.restore()
+
+	y := top + d.Height*k + 24
+	column := 0
+	canvas.fillColor(0.12, 0.16, 0.22)
+	for _, e := range entries {
+		if y > pdfMargin+t.areaH {
+			column++
+			y = top + d.Height*k + 24
+			if column == columns {
+				doc.addPage(canvas)
+				canvas = newPDFCanvas(t.pageH)
+				canvas.fillColor(0.12, 0.16, 0.22)
+				column = 0
+				y = pdfMargin + 12
+			}
+		}
+		x := pdfMargin + float64(column)*(columnW+24)
+		dots := int((columnW-pdfTextWidth(e.name+e.page, fontSize))/(layout.CharWidth*fontSize)) - 2
+		canvas.text(false, fontSize, x, y, e.name+" "+strings.Repeat(".", max(1, dots))+" "+e.page)
+		y += leading
+	}
+
+	doc.addPage(canvas)
+}
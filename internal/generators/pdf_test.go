@@ -0,0 +1,157 @@
+package generators
+
+import (
+	"compress/zlib"
+	"dbv/internal/layout"
+	"dbv/pkg/generator"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPDFOptions(t *testing.T) {
+	tests := []struct {
+		opts    generator.Options
+		wantErr string
+	}{
+		{opts: generator.Options{"paper": "Letter", "orientation": "landscape", "index": "never"}},
+		{opts: generator.Options{"scale": "0.5", "overlap": "0", "fit": "true"}},
+		{opts: generator.Options{"scale": "2", "overlap": "72.5"}},
+		{opts: generator.Options{"paper": "b5"}, wantErr: "option paper: unknown size 'b5'"},
+		{opts: generator.Options{"orientation": "upside-down"}, wantErr: "option orientation: invalid value"},
+		{opts: generator.Options{"index": "sometimes"}, wantErr: "option index: invalid value"},
+		{opts: generator.Options{"fit": "maybe"}, wantErr: "option fit: invalid boolean"},
+		{opts: generator.Options{"columns": "maybe"}, wantErr: "option columns: invalid boolean"},
+		{opts: generator.Options{"scale": "0"}, wantErr: "option scale: invalid positive number '0'"},
+		{opts: generator.Options{"scale": "-1"}, wantErr: "option scale: invalid positive number"},
+		{opts: generator.Options{"scale": "big"}, wantErr: "option scale: invalid positive number"},
+		{opts: generator.Options{"scale": "NaN"}, wantErr: "option scale: invalid positive number 'NaN'"},
+		{opts: generator.Options{"scale": "Inf"}, wantErr: "option scale: invalid positive number 'Inf'"},
+		{opts: generator.Options{"scale": "1e999"}, wantErr: "option scale: invalid positive number"},
+		{opts: generator.Options{"overlap": "-0.1"}, wantErr: "option overlap: invalid non-negative number '-0.1'"},
+		{opts: generator.Options{"overlap": "nan"}, wantErr: "option overlap: invalid non-negative number"},
+		{opts: generator.Options{"overlap": "-Inf"}, wantErr: "option overlap: invalid non-negative number"},
+	}
+	for _, tt := range tests {
+		_, err := generator.New("pdf", tt.opts)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("New(%v) error = %v", tt.opts, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("New(%v) error = %v, want %q", tt.opts, err, tt.wantErr)
+		}
+	}
+}
+
+// checkPDF checks the cross-reference table of a PDF and returns its page
+// count.
+func checkPDF(t *testing.T, out string) int {
+	t.Helper()
+
+	if !strings.HasPrefix(out, "%PDF-1.4\n") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Fatal("output is not a PDF file")
+	}
+
+	// The page contents only have finite numbers.
+	streams := regexp.MustCompile(`/Length (\d+) /Filter /FlateDecode >>\nstream\n`)
+	for _, loc := range streams.FindAllStringSubmatchIndex(out, -1) {
+		length, _ := strconv.Atoi(out[loc[2]:loc[3]])
+		r, err := zlib.NewReader(strings.NewReader(out[loc[1] : loc[1]+length]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(content), "NaN") || strings.Contains(string(content), "Inf") {
+			t.Errorf("page content has non-finite numbers:\n%s", content)
+		}
+		if !strings.HasPrefix(out[loc[1]+length:], "\nendstream\n") {
+			t.Error("stream length does not match its data")
+		}
+	}
+
+	match := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindStringSubmatch(out)
+	if match == nil {
+		t.Fatal("output lacks startxref")
+	}
+	xref, _ := strconv.Atoi(match[1])
+	if !strings.HasPrefix(out[xref:], "xref\n") {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	var size int
+	if _, err := fmt.Sscanf(out[xref:], "xref\n0 %d\n", &size); err != nil {
+		t.Fatal(err)
+	}
+	entries := strings.Split(out[xref:], "\n")[2:]
+	if entries[0] != "0000000000 65535 f " {
+		t.Errorf("first xref entry = %q", entries[0])
+	}
+	for num := 1; num < size; num++ {
+		var offset int
+		if _, err := fmt.Sscanf(entries[num], "%010d 00000 n ", &offset); err != nil {
+			t.Fatalf("xref entry %d = %q: %v", num, entries[num], err)
+		}
+		if want := fmt.Sprintf("%d 0 obj\n", num); !strings.HasPrefix(out[offset:], want) {
+			t.Errorf("xref entry %d points at %q", num, out[offset:min(offset+20, len(out))])
+		}
+	}
+	if !strings.Contains(out, fmt.Sprintf("trailer\n<< /Size %d ", size)) {
+		t.Errorf("trailer does not give the size %d", size)
+	}
+
+	pages := strings.Count(out, "<< /Type /Page ")
+	count := regexp.MustCompile(`/Type /Pages /Kids \[([^\]]*)\] /Count (\d+)`).FindStringSubmatch(out)
+	if count == nil {
+		t.Fatal("output lacks the page tree")
+	}
+	if kids := strings.Count(count[1], " 0 R"); kids != pages || count[2] != strconv.Itoa(pages) {
+		t.Errorf("page tree has %d kids and count %s, want %d", kids, count[2], pages)
+	}
+	return pages
+}
+
+// tiles returns the number of pages the diagram of refSchema is tiled over
+// with opts.
+func tiles(t *testing.T, opts generator.Options, landscape bool) int {
+	t.Helper()
+	g, err := newPDF(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf := g.(*pdfGenerator)
+	tiling := pdf.tile(layout.Compute(refSchema(), pdf.opts), landscape)
+	return tiling.rows * tiling.cols
+}
+
+func TestPDF(t *testing.T) {
+	tiled := generator.Options{"scale": "8", "orientation": "landscape"}
+	if n := tiles(t, tiled, true); n < 2 {
+		t.Fatalf("scale 8 gives %d pages, want a tiled diagram", n)
+	}
+	unlapped := generator.Options{"scale": "8", "orientation": "landscape", "overlap": "0", "index": "never"}
+
+	tests := []struct {
+		name  string
+		opts  generator.Options
+		pages int
+	}{
+		{"single page", generator.Options{}, 1},
+		{"index always", generator.Options{"index": "always"}, 2},
+		{"fit", generator.Options{"scale": "20", "fit": "true"}, 1},
+		{"tiled with an index", tiled, tiles(t, tiled, true) + 1},
+		{"tiled without overlap", unlapped, tiles(t, unlapped, true)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if pages := checkPDF(t, generate(t, "pdf", tt.opts, refSchema())); pages != tt.pages {
+				t.Errorf("pages = %d, want %d", pages, tt.pages)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package generators
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pdfDocument writes a minimal PDF 1.4 file: pages of vector graphics and
+// text in the standard Courier fonts, which need no embedding.
+type pdfDocument struct {
+	w       *bufio.Writer
+	n       int64
+	err     error
+	offsets map[int]int64
+	next    int
+	pages   []int
+	width   float64
+	height  float64
+}
+
+// Object numbers reserved by every document.
+const (
+	pdfCatalog = 1
+	pdfPages   = 2
+	pdfFont    = 3
+	pdfBold    = 4
+)
+
+func newPDFDocument(w io.Writer, width, height float64) *pdfDocument {
+	d := &pdfDocument{
+		w:       bufio.NewWriter(w),
+		offsets: make(map[int]int64),
+		next:    pdfBold + 1,
+		width:   width,
+		height:  height,
+	}
+	d.write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	return d
+}
+
+func (d *pdfDocument) write(s string) {
+	if d.err != nil {
+		return
+	}
+	n, err := d.w.WriteString(s)
+	d.n += int64(n)
+	d.err = err
+}
+
+func (d *pdfDocument) reserve() int {
+	d.next++
+	return d.next - 1
+}
+
+func (d *pdfDocument) object(num int, body string) {
+	d.offsets[num] = d.n
+	d.write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", num, body))
+}
+
+func (d *pdfDocument) stream(num int, data []byte) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(data)
+	zw.Close()
+
+	d.offsets[num] = d.n
+	d.write(fmt.Sprintf("%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", num, compressed.Len()))
+	d.write(compressed.String())
+	d.write("\nendstream\nendobj\n")
+}
+
+// addPage writes a page with the given content stream.
+func (d *pdfDocument) addPage(c *pdfCanvas) {
+	content := d.reserve()
+	d.stream(content, c.buf.Bytes())
+
+	page := d.reserve()
+	d.object(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Contents %d 0 R "+
+		"/Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> >>",
+		pdfPages, pdfNum(d.width), pdfNum(d.height), content, pdfFont, pdfBold))
+	d.pages = append(d.pages, page)
+}
+
+// close writes the shared objects, the cross-reference table and the
+// trailer, and flushes the output.
+func (d *pdfDocument) close(title string, created time.Time) error {
+	d.object(pdfFont, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	d.object(pdfBold, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+
+	kids := make([]string, len(d.pages))
+	for i, page := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	d.object(pdfPages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	d.object(pdfCatalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPages))
+
+	info := d.reserve()
+	d.object(info, fmt.Sprintf("<< /Title %s /Producer (dbv) /CreationDate (D:%s) >>",
+		pdfString(title), created.UTC().Format("20060102150405Z")))
+
+	xref := d.n
+	d.write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", d.next))
+	for num := 1; num < d.next; num++ {
+		d.write(fmt.Sprintf("%010d 00000 n \n", d.offsets[num]))
+	}
+	d.write(fmt.Sprintf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		d.next, pdfCatalog, info, xref))
+
+	if d.err != nil {
+		return d.err
+	}
+	return d.w.Flush()
+}
+
+// pdfCanvas builds a page content stream. Coordinates grow right and down
+// from the top left corner of the page, like the layout package's.
+type pdfCanvas struct {
+	buf bytes.Buffer
+}
+
+func newPDFCanvas(pageHeight float64) *pdfCanvas {
+	c := &pdfCanvas{}
+	c.op("1 0 0 -1 0 %s cm", pdfNum(pageHeight))
+	return c
+}
+
+func (c *pdfCanvas) op(format string, args ...any) {
+	fmt.Fprintf(&c.buf, format+"\n", args...)
+}
+
+func (c *pdfCanvas) save()    { c.op("q") }
+func (c *pdfCanvas) restore() { c.op("Q") }
+
+func (c *pdfCanvas) transform(scale, tx, ty float64) {
+	c.op("%s 0 0 %s %s %s cm", pdfNum(scale), pdfNum(scale), pdfNum(tx), pdfNum(ty))
+}
+
+func (c *pdfCanvas) clip(x, y, w, h float64) {
+	c.op("%s %s %s %s re W n", pdfNum(x), pdfNum(y), pdfNum(w), pdfNum(h))
+}
+
+func (c *pdfCanvas) fillColor(r, g, b float64) {
+	c.op("%s %s %s rg", pdfNum(r), pdfNum(g), pdfNum(b))
+}
+
+func (c *pdfCanvas) strokeColor(r, g, b float64) {
+	c.op("%s %s %s RG", pdfNum(r), pdfNum(g), pdfNum(b))
+}
+
+func (c *pdfCanvas) lineWidth(w float64) {
+	c.op("%s w", pdfNum(w))
+}
+
+// dash sets a dash pattern; no arguments select solid lines.
+func (c *pdfCanvas) dash(pattern ...float64) {
+	parts := make([]string, len(pattern))
+	for i, p := range pattern {
+		parts[i] = pdfNum(p)
+	}
+	c.op("[%s] 0 d", strings.Join(parts, " "))
+}
+
+// rect draws a rectangle, filled and/or stroked.
+func (c *pdfCanvas) rect(x, y, w, h float64, fill, stroke bool) {
+	c.op("%s %s %s %s re %s", pdfNum(x), pdfNum(y), pdfNum(w), pdfNum(h), paintOp(fill, stroke))
+}
+
+func (c *pdfCanvas) polyline(points ...[2]float64) {
+	for i, p := range points {
+		verb := "l"
+		if i == 0 {
+			verb = "m"
+		}
+		c.op("%s %s %s", pdfNum(p[0]), pdfNum(p[1]), verb)
+	}
+	c.op("S")
+}
+
+// circle draws a circle approximated by four Bézier curves.
+func (c *pdfCanvas) circle(x, y, r float64, fill, stroke bool) {
+	k := r * 4 * (math.Sqrt2 - 1) / 3
+	c.op("%s %s m", pdfNum(x+r), pdfNum(y))
+	c.op("%s %s %s %s %s %s c", pdfNum(x+r), pdfNum(y+k), pdfNum(x+k), pdfNum(y+r), pdfNum(x), pdfNum(y+r))
+	c.op("%s %s %s %s %s %s c", pdfNum(x-k), pdfNum(y+r), pdfNum(x-r), pdfNum(y+k), pdfNum(x-r), pdfNum(y))
+	c.op("%s %s %s %s %s %s c", pdfNum(x-r), pdfNum(y-k), pdfNum(x-k), pdfNum(y-r), pdfNum(x), pdfNum(y-r))
+	c.op("%s %s %s %s %s %s c", pdfNum(x+k), pdfNum(y-r), pdfNum(x+r), pdfNum(y-k), pdfNum(x+r), pdfNum(y))
+	c.op(paintOp(fill, stroke))
+}
+
+// text draws s with its baseline at y. The text matrix flips the y axis back
+// so glyphs are upright.
+func (c *pdfCanvas) text(bold bool, size, x, y float64, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	c.op("BT /%s %s Tf 1 0 0 -1 %s %s Tm %s Tj ET", font, pdfNum(size), pdfNum(x), pdfNum(y), pdfString(s))
+}
+
+func paintOp(fill, stroke bool) string {
+	switch {
+	case fill && stroke:
+		return "B"
+	case fill:
+		return "f"
+	default:
+		return "S"
+	}
+}
+
+// pdfNum formats a number with at most three decimals.
+func pdfNum(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1000)/1000, 'f', -1, 64)
+}
+
+// winAnsi maps the characters outside Latin-1 that WinAnsiEncoding has.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91,
+	'’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98,
+	'™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// pdfString encodes s as a literal string in WinAnsiEncoding; characters it
+// cannot represent become '?'.
+func pdfString(s string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		case winAnsi[r] != 0:
+			fmt.Fprintf(&b, "\\%03o", winAnsi[r])
+		default:
+			b.WriteByte('?')
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
@@ -1,28 +1,49 @@
 package generators
 
 import (
-	"dbv/internal/schema"
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
 	"fmt"
+	"io"
 	"strings"
 )
 
-func GeneratePlantUML(s *schema.Schema) string {
-    var builder strings.Builder
+func init() {
+    generator.Register("plantuml", newPlantUML)
+}
+
+// plantUMLGenerator renders PlantUML entities.
+type plantUMLGenerator struct{}
+
+func newPlantUML(generator.Options) (generator.Generator, error) {
+    return &plantUMLGenerator{}, nil
+}
+
+func (g *plantUMLGenerator) Name() string        { return "plantuml" }
+func (g *plantUMLGenerator) Description() string { return "PlantUML" }
+func (g *plantUMLGenerator) Extension() string   { return ".puml" }
+
+func (g *plantUMLGenerator) Options() []generator.Option { return nil }
+
+func (g *plantUMLGenerator) Generate(w io.Writer, s *schema.Schema) error {
+    out := newWriter(w)
+    qualify := s.Qualifier()
 
-    builder.WriteString("@startuml\n")
-    builder.WriteString("!theme plain\n")
-    builder.WriteString("skinparam linetype ortho\n\n")
+    out.print("@startuml\n")
+    out.print("!theme plain\n")
+    out.print("skinparam linetype ortho\n\n")
 
     for _, table := range s.Tables {
-        builder.WriteString(fmt.Sprintf("entity \"%s\" as %s {\n", table.Name, cleanEntityName(table.Name)))
+        name := qualify(table.Schema, table.Name)
+        out.printf("entity \"%s\" as %s {\n", name, cleanEntityName(name))
         
         for _, col := range table.Columns {
             if col.IsPrimaryKey {
-                builder.WriteString(fmt.Sprintf("  * %s : %s <<PK>>\n", col.Name, formatPlantUMLType(col)))
+                out.printf("  * %s : %s <<PK>>\n", col.Name, formatPlantUMLType(col))
             }
         }
         
-        builder.WriteString("  --\n")
+        out.print("  --\n")
         
         for _, col := range table.Columns {
             if !col.IsPrimaryKey {
@@ -30,33 +51,34 @@ func GeneratePlantUML(s *schema.Schema) string {
                 if !col.IsNullable {
                     nullStr = " <<NOT NULL>>"
                 }
-                builder.WriteString(fmt.Sprintf("  %s : %s%s\n", col.Name, formatPlantUMLType(col), nullStr))
+                out.printf("  %s : %s%s\n", col.Name, formatPlantUMLType(col), nullStr)
             }
         }
         
-        builder.WriteString("}\n\n")
+        out.print("}\n\n")
     }
 
     for _, view := range s.Views {
-        builder.WriteString(fmt.Sprintf("entity \"%s\" as %s <<view>> {\n", view.Name, cleanEntityName(view.Name)))
+        name := qualify(view.Schema, view.Name)
+        out.printf("entity \"%s\" as %s <<view>> {\n", name, cleanEntityName(name))
         
         for _, col := range view.Columns {
-            builder.WriteString(fmt.Sprintf("  %s : %s\n", col.Name, formatPlantUMLType(col)))
+            out.printf("  %s : %s\n", col.Name, formatPlantUMLType(col))
         }
         
-        builder.WriteString("}\n\n")
+        out.print("}\n\n")
     }
 
     for _, fk := range s.ForeignKeys {
-        builder.WriteString(fmt.Sprintf("%s ||--o{ %s : %s\n", 
-            cleanEntityName(fk.ReferencedTable), 
-            cleanEntityName(fk.Table), 
-            fk.Column))
+        out.printf("%s ||--o{ %s : %s\n", 
+            cleanEntityName(qualify(fk.ReferencedSchema, fk.ReferencedTable)), 
+            cleanEntityName(qualify(fk.Schema, fk.Table)), 
+            fk.Column)
     }
 
-    builder.WriteString("\n@enduml\n")
+    out.print("\n@enduml\n")
 
-    return builder.String()
+    return out.flush()
 }
 
 func formatPlantUMLType(col schema.Column) string {
@@ -0,0 +1,642 @@
+package generators
+
+import (
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+func init() {
+	generator.Register("sql", newSQL)
+}
+
+// sqlGenerator writes DDL that recreates the schema in PostgreSQL or SQLite:
+// enums, tables with their keys and foreign keys, indexes, views and
+// comments. Tables are created after the tables they reference and views
+// after the views they select from, so the script runs from top to bottom.
+type sqlGenerator struct {
+	dialect string
+}
+
+func newSQL(opts generator.Options) (generator.Generator, error) {
+	dialect := strings.ToLower(opts.String("dialect", ""))
+	switch dialect {
+	case "", "postgres", "sqlite":
+	case "postgresql":
+		dialect = "postgres"
+	case "sqlite3":
+		dialect = "sqlite"
+	default:
+		return nil, fmt.Errorf("option dialect: invalid value '%s'. Valid values: postgres, sqlite", dialect)
+	}
+	return &sqlGenerator{dialect: dialect}, nil
+}
+
+func (g *sqlGenerator) Name() string        { return "sql" }
+func (g *sqlGenerator) Description() string { return "SQL DDL (PostgreSQL or SQLite)" }
+func (g *sqlGenerator) Extension() string   { return ".sql" }
+
+func (g *sqlGenerator) Options() []generator.Option {
+	return []generator.Option{
+		{Name: "dialect", Description: "postgres or sqlite; defaults to the source database, or postgres", Default: ""},
+	}
+}
+
+func (g *sqlGenerator) Generate(w io.Writer, s *schema.Schema) error {
+	d := newSQLDialect(g.dialect, s)
+	out := newWriter(w)
+
+	out.printf("-- %s schema, generated by dbv on %s for %s\n", s.Database, s.GeneratedAt.Format("2006-01-02 15:04:05"), d.title())
+	out.print("\nBEGIN;\n")
+
+	if d.postgres {
+		var schemas []string
+		for _, table := range s.Tables {
+			if d.qualified(table.Schema) && table.Schema != "public" && !slices.Contains(schemas, table.Schema) {
+				schemas = append(schemas, table.Schema)
+			}
+		}
+		for _, view := range s.Views {
+			if d.qualified(view.Schema) && view.Schema != "public" && !slices.Contains(schemas, view.Schema) {
+				schemas = append(schemas, view.Schema)
+			}
+		}
+		if len(schemas) > 0 {
+			out.print("\n")
+		}
+		for _, name := range schemas {
+			out.printf("CREATE SCHEMA IF NOT EXISTS %s;\n", d.quote(name))
+		}
+
+		for _, enum := range s.Enums {
+			values := make([]string, len(enum.Values))
+			for i, value := range enum.Values {
+				values[i] = sqlString(value)
+			}
+			out.printf("\nCREATE TYPE %s AS ENUM (%s);\n", d.name(enum.Schema, enum.Name), strings.Join(values, ", "))
+			if enum.Comment != "" {
+				out.printf("COMMENT ON TYPE %s IS %s;\n", d.name(enum.Schema, enum.Name), sqlString(enum.Comment))
+			}
+		}
+	}
+
+	refs := make(map[[2]string][]*dbmlRef)
+	for _, ref := range dbmlRefs(s) {
+		key := [2]string{ref.fk.Schema, ref.fk.Table}
+		refs[key] = append(refs[key], ref)
+	}
+
+	tables := sqlTableOrder(s.Tables, refs)
+	created := make(map[[2]string]bool)
+	extracted := make(map[[2]string]bool)
+	for _, table := range s.Tables {
+		extracted[[2]string{table.Schema, table.Name}] = true
+	}
+
+	// Foreign keys to tables that come later, because of a cycle, are added
+	// once all tables exist. SQLite does not check references when a table
+	// is created, so it keeps them in the table.
+	var deferred []*dbmlRef
+	for _, table := range tables {
+		key := [2]string{table.Schema, table.Name}
+		created[key] = true
+
+		var inline []*dbmlRef
+		var notes []string
+		for _, ref := range refs[key] {
+			target := [2]string{ref.fk.ReferencedSchema, ref.fk.ReferencedTable}
+			switch {
+			case !extracted[target]:
+				notes = append(notes, fmt.Sprintf("%s references %s, which was not extracted", strings.Join(ref.columns, ", "),
+					d.name(target[0], target[1])))
+			case !d.postgres && target[0] != table.Schema:
+				notes = append(notes, fmt.Sprintf("%s references %s in another database, which SQLite cannot enforce",
+					strings.Join(ref.columns, ", "), d.name(target[0], target[1])))
+			case d.postgres && !created[target]:
+				deferred = append(deferred, ref)
+			default:
+				inline = append(inline, ref)
+			}
+		}
+
+		g.writeTable(out, d, s, table, inline, notes)
+	}
+
+	for _, ref := range deferred {
+		out.printf("\nALTER TABLE %s ADD %s;\n", d.name(ref.fk.Schema, ref.fk.Table), d.foreignKey(ref))
+	}
+
+	var indexes []schema.Index
+	for _, index := range s.Indexes {
+		if !index.IsPrimary && !strings.HasPrefix(index.Name, "sqlite_autoindex_") && extracted[[2]string{index.Schema, index.Table}] {
+			indexes = append(indexes, index)
+		}
+	}
+	if len(indexes) > 0 {
+		out.print("\n")
+	}
+	for _, index := range indexes {
+		g.writeIndex(out, d, index)
+	}
+
+	for _, view := range sqlViewOrder(s.Views) {
+		name := d.name(view.Schema, view.Name)
+		out.print("\n")
+		if !d.postgres && view.Comment != "" {
+			writeSQLLineComment(out, "", view.Comment)
+		}
+		out.printf("CREATE VIEW %s AS\n%s;\n", name, sqlViewQuery(view.Definition))
+		if d.postgres && view.Comment != "" {
+			out.printf("COMMENT ON VIEW %s IS %s;\n", name, sqlString(view.Comment))
+		}
+	}
+
+	out.print("\nCOMMIT;\n")
+
+	return out.flush()
+}
+
+func (g *sqlGenerator) writeTable(out *writer, d sqlDialect, s *schema.Schema, table schema.Table, refs []*dbmlRef, notes []string) {
+	name := d.name(table.Schema, table.Name)
+
+	var lines []string
+	for _, col := range table.Columns {
+		line := "  " + d.quote(col.Name) + " " + d.columnType(col)
+		if value := d.defaultValue(col); value != "" {
+			line += " DEFAULT " + value
+		}
+		// PostgreSQL makes primary key columns NOT NULL by itself.
+		if !col.IsNullable && !(d.postgres && slices.Contains(table.PrimaryKeys, col.Name)) {
+			line += " NOT NULL"
+		}
+		if col.IsUnique && !sqlUniqueIndex(s, table, []string{col.Name}) {
+			line += " UNIQUE"
+		}
+		if enum := d.enum(col); enum != nil && !d.postgres {
+			values := make([]string, len(enum.Values))
+			for i, value := range enum.Values {
+				values[i] = sqlString(value)
+			}
+			line += fmt.Sprintf(" CHECK (%s IN (%s))", d.quote(col.Name), strings.Join(values, ", "))
+		}
+		if !d.postgres && col.Comment != "" {
+			line = sqlLineComment("  ", col.Comment) + line
+		}
+		lines = append(lines, line)
+	}
+
+	if len(table.PrimaryKeys) > 0 {
+		line := "  "
+		for _, index := range s.Indexes {
+			if index.IsPrimary && index.Table == table.Name && index.Schema == table.Schema && d.source == "postgresql" && d.postgres {
+				line += "CONSTRAINT " + d.quote(index.Name) + " "
+				break
+			}
+		}
+		lines = append(lines, line+"PRIMARY KEY ("+d.quoteAll(table.PrimaryKeys)+")")
+	}
+
+	// Unique constraints over several columns are only visible through the
+	// indexes SQLite creates for them. Redaction can leave them without
+	// columns.
+	for _, index := range s.Indexes {
+		if index.Table == table.Name && index.Schema == table.Schema && !index.IsPrimary && len(index.Columns) > 0 &&
+			strings.HasPrefix(index.Name, "sqlite_autoindex_") && !slices.Contains(index.Columns, "(expression)") {
+			lines = append(lines, "  UNIQUE ("+d.quoteAll(index.Columns)+")")
+		}
+	}
+
+	for _, ref := range refs {
+		lines = append(lines, "  "+d.foreignKey(ref))
+	}
+
+	out.print("\n")
+	if !d.postgres && table.Comment != "" {
+		writeSQLLineComment(out, "", table.Comment)
+	}
+	out.printf("CREATE TABLE %s (\n", name)
+	out.print(strings.Join(lines, ",\n"))
+	for _, note := range notes {
+		out.printf("\n  -- %s", note)
+	}
+	out.print("\n);\n")
+
+	if d.postgres {
+		if table.Comment != "" {
+			out.printf("COMMENT ON TABLE %s IS %s;\n", name, sqlString(table.Comment))
+		}
+		for _, col := range table.Columns {
+			if col.Comment != "" {
+				out.printf("COMMENT ON COLUMN %s.%s IS %s;\n", name, d.quote(col.Name), sqlString(col.Comment))
+			}
+		}
+	}
+}
+
+func (g *sqlGenerator) writeIndex(out *writer, d sqlDialect, index schema.Index) {
+	if len(index.Columns) == 0 {
+		out.printf("-- Index %s has no columns left after redaction\n", d.quote(index.Name))
+		return
+	}
+
+	// Expressions are written in the SQL of the source database.
+	native := d.postgres == (d.source == "postgresql")
+	columns := make([]string, len(index.Columns))
+	for i, col := range index.Columns {
+		switch {
+		case col == "(expression)":
+			out.printf("-- Index %s is on an expression that was not extracted\n", d.quote(index.Name))
+			return
+		case sqlSQLiteName.MatchString(col):
+			columns[i] = d.quote(col)
+		case !native:
+			out.printf("-- Index %s is on the %s expression %s\n", d.quote(index.Name), d.source, strings.Join(strings.Fields(col), " "))
+			return
+		default:
+			columns[i] = col
+		}
+	}
+
+	unique := ""
+	if index.IsUnique {
+		unique = "UNIQUE "
+	}
+	// PostgreSQL creates the index in the schema of its table, so only the
+	// table is qualified.
+	name := d.quote(index.Name)
+	if !d.postgres {
+		name = d.name(index.Schema, index.Name)
+	}
+	out.printf("CREATE %sINDEX %s ON %s", unique, name, d.tableName(index.Schema, index.Table))
+	if method := strings.ToLower(index.Type); d.postgres && method != "" && method != "btree" {
+		out.printf(" USING %s", method)
+	}
+	out.printf(" (%s);\n", strings.Join(columns, ", "))
+}
+
+// sqlDialect holds what the statements depend on: the target database and
+// how the source database names things.
+type sqlDialect struct {
+	postgres bool
+	// source is the database the schema was extracted from.
+	source  string
+	qualify func(schemaName, name string) string
+	enums   map[string]*schema.Enum
+}
+
+func newSQLDialect(dialect string, s *schema.Schema) sqlDialect {
+	if dialect == "" {
+		dialect = "postgres"
+		if s.Database == "sqlite" {
+			dialect = "sqlite"
+		}
+	}
+
+	d := sqlDialect{
+		postgres: dialect == "postgres",
+		source:   s.Database,
+		qualify:  s.Qualifier(),
+		enums:    make(map[string]*schema.Enum),
+	}
+	// A SQLite file has no schemas of its own, so only attached databases
+	// of a SQLite source are kept apart.
+	if !d.postgres && s.Database != "sqlite" {
+		d.qualify = func(_, name string) string { return name }
+	}
+	for i := range s.Enums {
+		enum := &s.Enums[i]
+		d.enums[enum.Name] = enum
+		d.enums[enum.Schema+"."+enum.Name] = enum
+	}
+	return d
+}
+
+func (d sqlDialect) title() string {
+	if d.postgres {
+		return "PostgreSQL"
+	}
+	return "SQLite"
+}
+
+// qualified reports whether names in the schema are prefixed with it.
+func (d sqlDialect) qualified(schemaName string) bool {
+	return schemaName != "" && d.qualify(schemaName, "x") != "x"
+}
+
+func (d sqlDialect) name(schemaName, name string) string {
+	if d.qualified(schemaName) {
+		return d.quote(schemaName) + "." + d.quote(name)
+	}
+	return d.quote(name)
+}
+
+// tableName names a table in a statement on it, such as CREATE INDEX.
+// SQLite takes the database from the index name instead.
+func (d sqlDialect) tableName(schemaName, name string) string {
+	if d.postgres {
+		return d.name(schemaName, name)
+	}
+	return d.quote(name)
+}
+
+var (
+	sqlPostgresName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+	sqlSQLiteName   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// sqlKeywords are reserved words that are quoted when used as names.
+var sqlKeywords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true, "array": true, "as": true, "asc": true,
+	"both": true, "case": true, "cast": true, "check": true, "collate": true, "column": true, "constraint": true,
+	"create": true, "current_date": true, "current_time": true, "current_timestamp": true, "current_user": true,
+	"default": true, "deferrable": true, "desc": true, "distinct": true, "do": true, "else": true, "end": true,
+	"except": true, "false": true, "fetch": true, "for": true, "foreign": true, "from": true, "grant": true,
+	"group": true, "having": true, "in": true, "index": true, "initially": true, "intersect": true, "into": true,
+	"is": true, "join": true, "key": true, "leading": true, "limit": true, "natural": true, "not": true,
+	"null": true, "offset": true, "on": true, "only": true, "or": true, "order": true, "placing": true,
+	"primary": true, "references": true, "returning": true, "select": true, "session_user": true, "some": true,
+	"symmetric": true, "table": true, "then": true, "to": true, "trailing": true, "true": true, "union": true,
+	"unique": true, "user": true, "using": true, "values": true, "variadic": true, "when": true, "where": true,
+	"window": true, "with": true,
+}
+
+// quote double-quotes a name unless it is a plain identifier that the
+// database reads back unchanged: PostgreSQL folds unquoted names to lower
+// case.
+func (d sqlDialect) quote(name string) string {
+	bare := sqlSQLiteName
+	if d.postgres {
+		bare = sqlPostgresName
+	}
+	if bare.MatchString(name) && !sqlKeywords[strings.ToLower(name)] {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (d sqlDialect) quoteAll(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = d.quote(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func (d sqlDialect) foreignKey(ref *dbmlRef) string {
+	fk := ref.fk
+	var b strings.Builder
+	// Other databases than PostgreSQL leave foreign keys unnamed, and the
+	// names dbv makes up for them are left out.
+	if fk.Name != "" && d.source == "postgresql" {
+		b.WriteString("CONSTRAINT " + d.quote(fk.Name) + " ")
+	}
+	target := d.quote(fk.ReferencedTable)
+	if d.postgres {
+		target = d.name(fk.ReferencedSchema, fk.ReferencedTable)
+	}
+	fmt.Fprintf(&b, "FOREIGN KEY (%s) REFERENCES %s (%s)", d.quoteAll(ref.columns), target, d.quoteAll(ref.targets))
+	if action := dbmlAction(fk.OnDelete); action != "" {
+		b.WriteString(" ON DELETE " + strings.ToUpper(action))
+	}
+	if action := dbmlAction(fk.OnUpdate); action != "" {
+		b.WriteString(" ON UPDATE " + strings.ToUpper(action))
+	}
+	return b.String()
+}
+
+func (d sqlDialect) enum(col schema.Column) *schema.Enum {
+	return d.enums[col.Type]
+}
+
+// sqlSerial matches the defaults of columns backed by a sequence.
+var sqlSerial = regexp.MustCompile(`^nextval\(.*\)$`)
+
+// columnType converts the type of a column to the target database. SQLite
+// accepts any type name, so types from PostgreSQL are kept where they parse,
+// while types of other databases map to their PostgreSQL names.
+func (d sqlDialect) columnType(col schema.Column) string {
+	t := col.Type
+	switch {
+	case col.Length != nil && *col.Length > 0 && !strings.Contains(t, "("):
+		t = fmt.Sprintf("%s(%d)", t, *col.Length)
+	case col.Precision != nil && col.Scale != nil && isDecimal(t):
+		t = fmt.Sprintf("%s(%d,%d)", t, *col.Precision, *col.Scale)
+	}
+
+	if !d.postgres {
+		switch {
+		case d.enum(col) != nil:
+			return "text"
+		case strings.HasSuffix(t, "[]"), t == "":
+			return "text"
+		}
+		return t
+	}
+
+	if col.DefaultValue != nil && sqlSerial.MatchString(strings.TrimSpace(*col.DefaultValue)) {
+		switch strings.ToLower(t) {
+		case "integer", "int", "int4":
+			return "serial"
+		case "bigint", "int8":
+			return "bigserial"
+		case "smallint", "int2":
+			return "smallserial"
+		}
+	}
+	if d.source == "postgresql" || d.enum(col) != nil {
+		return t
+	}
+
+	base, args, _ := strings.Cut(strings.ToLower(t), "(")
+	if args != "" {
+		args = "(" + args
+	}
+	switch strings.TrimSpace(base) {
+	case "":
+		return "text"
+	case "int", "integer", "mediumint":
+		return "integer"
+	case "tinyint", "smallint", "int2", "utinyint":
+		return "smallint"
+	case "bigint", "int8", "usmallint", "uinteger":
+		return "bigint"
+	case "ubigint", "hugeint", "uhugeint":
+		return "numeric"
+	case "real", "float", "double", "float8", "double precision":
+		return "double precision"
+	case "float4":
+		return "real"
+	case "datetime":
+		return "timestamp"
+	case "blob", "bytea", "varbinary", "binary":
+		return "bytea"
+	case "bool", "boolean":
+		return "boolean"
+	case "string", "clob":
+		return "text"
+	}
+	return strings.TrimSpace(base) + args
+}
+
+var (
+	sqlNow        = regexp.MustCompile(`(?i)^\(?\s*(now\(\)|current_timestamp|datetime\('now'\)|transaction_timestamp\(\))\s*\)?$`)
+	sqlCastString = regexp.MustCompile(`^('(?:[^']|'')*')::[A-Za-z0-9_ ."\[\]]+$`)
+)
+
+// defaultValue converts the default of a column to the target database,
+// or returns "" when there is none to write.
+func (d sqlDialect) defaultValue(col schema.Column) string {
+	if col.DefaultValue == nil {
+		return ""
+	}
+	value := strings.TrimSpace(*col.DefaultValue)
+	switch {
+	case value == "" || sqlSerial.MatchString(value):
+		// Sequences become serial types in PostgreSQL, while SQLite numbers
+		// integer primary keys by itself.
+		return ""
+	case sqlNow.MatchString(value):
+		return "CURRENT_TIMESTAMP"
+	case d.postgres:
+		return value
+	}
+
+	if m := sqlCastString.FindStringSubmatch(value); m != nil {
+		return m[1]
+	}
+	if dbmlNumber.MatchString(value) || strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+		return value
+	}
+	switch strings.ToLower(value) {
+	case "null", "true", "false", "current_date", "current_time":
+		return value
+	}
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		return value
+	}
+	return "(" + value + ")"
+}
+
+// sqlTableOrder sorts tables so that every table comes after the tables it
+// references, keeping the extracted order otherwise.
+func sqlTableOrder(tables []schema.Table, refs map[[2]string][]*dbmlRef) []schema.Table {
+	return sqlDependencyOrder(tables, func(table schema.Table) [2]string {
+		return [2]string{table.Schema, table.Name}
+	}, func(table schema.Table) [][2]string {
+		var deps [][2]string
+		for _, ref := range refs[[2]string{table.Schema, table.Name}] {
+			deps = append(deps, [2]string{ref.fk.ReferencedSchema, ref.fk.ReferencedTable})
+		}
+		return deps
+	})
+}
+
+// sqlViewOrder sorts views so that a view whose definition mentions another
+// view comes after it.
+func sqlViewOrder(views []schema.View) []schema.View {
+	return sqlDependencyOrder(views, func(view schema.View) [2]string {
+		return [2]string{view.Schema, view.Name}
+	}, func(view schema.View) [][2]string {
+		var deps [][2]string
+		for _, other := range views {
+			pattern := `(?i)(^|[^A-Za-z0-9_$])"?` + regexp.QuoteMeta(other.Name) + `"?($|[^A-Za-z0-9_$])`
+			if matched, _ := regexp.MatchString(pattern, sqlViewQuery(view.Definition)); matched {
+				deps = append(deps, [2]string{other.Schema, other.Name})
+			}
+		}
+		return deps
+	})
+}
+
+func sqlDependencyOrder[T any](items []T, key func(T) [2]string, deps func(T) [][2]string) []T {
+	byKey := make(map[[2]string]T)
+	for _, item := range items {
+		byKey[key(item)] = item
+	}
+
+	// Each item is preceded by what it depends on. An item that is reached
+	// again while its dependencies are being visited is part of a cycle and
+	// comes after the item that reached it.
+	var ordered []T
+	state := make(map[[2]string]int)
+	const visiting, done = 1, 2
+	var visit func(item T)
+	visit = func(item T) {
+		k := key(item)
+		if state[k] != 0 {
+			return
+		}
+		state[k] = visiting
+		for _, dep := range deps(item) {
+			if next, ok := byKey[dep]; ok {
+				visit(next)
+			}
+		}
+		state[k] = done
+		ordered = append(ordered, item)
+	}
+
+	for _, item := range items {
+		visit(item)
+	}
+	return ordered
+}
+
+// sqlUniqueIndex reports whether a unique index covers exactly the columns,
+// in which case the index is created instead of a column constraint.
+func sqlUniqueIndex(s *schema.Schema, table schema.Table, columns []string) bool {
+	for _, index := range s.Indexes {
+		if index.IsUnique && index.Table == table.Name && index.Schema == table.Schema &&
+			slices.Equal(index.Columns, columns) {
+			return true
+		}
+	}
+	return false
+}
+
+var sqlCreateView = regexp.MustCompile(`(?is)^\s*CREATE\s+(?:OR\s+REPLACE\s+)?(?:TEMP(?:ORARY)?\s+)?VIEW\s+(?:IF\s+NOT\s+EXISTS\s+)?` +
+	`(?:"(?:[^"]|"")*"|[^\s(]+)(?:\s*\([^)]*\))?\s+AS\s+(.*)$`)
+
+// sqlViewQuery returns the query of a view definition, which is the query
+// itself in PostgreSQL and the whole CREATE VIEW statement in SQLite and
+// DuckDB.
+func sqlViewQuery(definition string) string {
+	if m := sqlCreateView.FindStringSubmatch(definition); m != nil {
+		definition = m[1]
+	}
+	definition = strings.TrimSpace(definition)
+	// SQLite keeps comments that follow the statement.
+	for strings.HasSuffix(definition, ";") || strings.HasSuffix(definition, "*/") {
+		if strings.HasSuffix(definition, ";") {
+			definition = strings.TrimSpace(strings.TrimSuffix(definition, ";"))
+			continue
+		}
+		start := strings.LastIndex(definition, "/*")
+		if start < 0 {
+			break
+		}
+		definition = strings.TrimSpace(definition[:start])
+	}
+	return definition
+}
+
+// sqlString writes a string literal.
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func sqlLineComment(indent, comment string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(comment, "\n") {
+		b.WriteString(indent + "-- " + strings.TrimRight(line, "\r") + "\n")
+	}
+	return b.String()
+}
+
+func writeSQLLineComment(out *writer, indent, comment string) {
+	out.print(sqlLineComment(indent, comment))
+}
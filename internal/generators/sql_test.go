@@ -0,0 +1,168 @@
+package generators
+
+import (
+	"bytes"
+	"database/sql"
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func generateSQL(t *testing.T, s *schema.Schema, dialect string) string {
+	t.Helper()
+	g, err := newSQL(generator.Options{"dialect": dialect})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := g.Generate(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// sqliteSchema is a schema as the SQLite extractor returns it, after the
+// email column was hidden.
+func sqliteSchema() *schema.Schema {
+	length := 100
+	return &schema.Schema{
+		Database:    "sqlite",
+		GeneratedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Tables: []schema.Table{
+			{
+				Name: "order", Schema: "main", PrimaryKeys: []string{"id"}, Comment: "Orders\nof users",
+				Columns: []schema.Column{
+					{Name: "id", Type: "INTEGER", IsPrimaryKey: true},
+					{Name: "user_id", Type: "INTEGER", IsNullable: true},
+					{Name: "status", Type: "TEXT", DefaultValue: ptr("'new'")},
+				},
+			},
+			{
+				Name: "users", Schema: "main", PrimaryKeys: []string{"id"},
+				Columns: []schema.Column{
+					{Name: "id", Type: "INTEGER", IsPrimaryKey: true},
+					{Name: "name", Type: "VARCHAR", Length: &length, Comment: "display name"},
+					{Name: "team", Type: "TEXT", IsNullable: true},
+				},
+			},
+		},
+		Views: []schema.View{
+			{Name: "open_orders", Schema: "main", Definition: `CREATE VIEW open_orders AS SELECT * FROM "order" WHERE status = 'new'`},
+		},
+		ForeignKeys: []schema.ForeignKey{
+			{Name: "fk_order_user_id", Schema: "main", Table: "order", Column: "user_id",
+				ReferencedSchema: "main", ReferencedTable: "users", ReferencedColumn: "id", OnDelete: "CASCADE"},
+		},
+		Indexes: []schema.Index{
+			{Name: "sqlite_autoindex_users_1", Schema: "main", Table: "users", Columns: []string{"name", "team"}, IsUnique: true},
+			{Name: "sqlite_autoindex_users_2", Schema: "main", Table: "users", Columns: []string{}, IsUnique: true},
+			{Name: "idx_users_redacted", Schema: "main", Table: "users", Columns: []string{}},
+			{Name: "idx_order_status", Schema: "main", Table: "order", Columns: []string{"status", "user_id"}},
+		},
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestSQLRunsInSQLite(t *testing.T) {
+	ddl := generateSQL(t, sqliteSchema(), "sqlite")
+	if strings.Contains(ddl, "()") {
+		t.Errorf("DDL has an empty column list:\n%s", ddl)
+	}
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ddl); err != nil {
+		t.Fatalf("DDL does not run: %v\n%s", err, ddl)
+	}
+
+	var objects []string
+	rows, err := db.Query("SELECT type || ' ' || name FROM sqlite_master WHERE name NOT LIKE 'sqlite_%' ORDER BY type, name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var object string
+		if err := rows.Scan(&object); err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, object)
+	}
+	want := "index idx_order_status, table order, table users, view open_orders"
+	if got := strings.Join(objects, ", "); got != want {
+		t.Errorf("objects = %s, want %s", got, want)
+	}
+
+	// The constraints were recreated too.
+	statements := []string{
+		`INSERT INTO users (id, name, team) VALUES (1, 'a', 'x')`,
+		`INSERT INTO "order" (id, user_id) VALUES (1, 1)`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+	for _, statement := range []string{
+		`INSERT INTO users (id, name, team) VALUES (2, 'a', 'x')`,
+		`INSERT INTO "order" (id, user_id) VALUES (2, 9)`,
+	} {
+		if _, err := db.Exec(statement); err == nil {
+			t.Errorf("%s succeeded, want a constraint error", statement)
+		}
+	}
+}
+
+func TestSQLDefersForeignKeyCycles(t *testing.T) {
+	s := &schema.Schema{
+		Database: "postgresql",
+		Tables: []schema.Table{
+			{Name: "departments", Schema: "public", PrimaryKeys: []string{"id"}, Columns: []schema.Column{
+				{Name: "id", Type: "integer", IsPrimaryKey: true},
+				{Name: "manager_id", Type: "integer", IsNullable: true},
+			}},
+			{Name: "employees", Schema: "public", PrimaryKeys: []string{"id"}, Columns: []schema.Column{
+				{Name: "id", Type: "integer", IsPrimaryKey: true},
+				{Name: "department_id", Type: "integer"},
+			}},
+		},
+		ForeignKeys: []schema.ForeignKey{
+			{Name: "departments_manager_fk", Schema: "public", Table: "departments", Column: "manager_id",
+				ReferencedSchema: "public", ReferencedTable: "employees", ReferencedColumn: "id"},
+			{Name: "employees_department_fk", Schema: "public", Table: "employees", Column: "department_id",
+				ReferencedSchema: "public", ReferencedTable: "departments", ReferencedColumn: "id", OnDelete: "CASCADE"},
+		},
+	}
+
+	ddl := generateSQL(t, s, "postgres")
+
+	// The cycle is broken at the first table created: employees references
+	// departments, which does not exist yet.
+	employees := strings.Index(ddl, "CREATE TABLE employees")
+	departments := strings.Index(ddl, "CREATE TABLE departments")
+	alter := strings.Index(ddl, "ALTER TABLE employees ADD CONSTRAINT employees_department_fk "+
+		"FOREIGN KEY (department_id) REFERENCES departments (id) ON DELETE CASCADE;")
+	if departments < 0 || employees < 0 || alter < 0 {
+		t.Fatalf("missing statements:\n%s", ddl)
+	}
+	if !(employees < departments && departments < alter) {
+		t.Errorf("want employees, departments, then the deferred foreign key:\n%s", ddl)
+	}
+	if strings.Count(ddl, "employees_department_fk") != 1 {
+		t.Errorf("deferred foreign key is also inline:\n%s", ddl)
+	}
+	if !strings.Contains(ddl[departments:alter], "CONSTRAINT departments_manager_fk FOREIGN KEY (manager_id) REFERENCES employees (id)") {
+		t.Errorf("departments lacks its inline foreign key:\n%s", ddl)
+	}
+}
@@ -0,0 +1,223 @@
+package generators
+
+import (
+	"dbv/internal/layout"
+	"dbv/pkg/generator"
+	"dbv/pkg/schema"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	generator.Register("svg", newSVG)
+}
+
+// svgGenerator draws the laid out ER diagram as a standalone SVG image,
+// without any external renderer.
+type svgGenerator struct {
+	opts layout.Options
+}
+
+func newSVG(opts generator.Options) (generator.Generator, error) {
+	lopts, err := layoutOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &svgGenerator{opts: lopts}, nil
+}
+
+// layoutOptions reads the options shared by the formats drawn with the
+// layout package.
+func layoutOptions(opts generator.Options) (layout.Options, error) {
+	columns, err := opts.Bool("columns", true)
+	if err != nil {
+		return layout.Options{}, err
+	}
+	types, err := opts.Bool("types", true)
+	if err != nil {
+		return layout.Options{}, err
+	}
+	return layout.Options{Columns: columns, Types: types}, nil
+}
+
+var layoutOptionList = []generator.Option{
+	{Name: "columns", Description: "Draw the columns of each table", Default: "true"},
+	{Name: "types", Description: "Show column types", Default: "true"},
+}
+
+func (g *svgGenerator) Name() string                { return "svg" }
+func (g *svgGenerator) Description() string         { return "SVG image" }
+func (g *svgGenerator) Extension() string           { return ".svg" }
+func (g *svgGenerator) Options() []generator.Option { return layoutOptionList }
+
+const svgStyle = `
+  text { font-family: ui-monospace, "DejaVu Sans Mono", Menlo, Consolas, monospace; font-size: 12px; fill: #1f2937; }
+  .box { fill: #ffffff; stroke: #64748b; stroke-width: 1; }
+  .header { fill: #dbeafe; stroke: #64748b; stroke-width: 1; }
+  .view .header { fill: #dcfce7; }
+  .collapsed .header { fill: #e5e7eb; }
+  .title { font-weight: bold; }
+  .view .title { font-style: italic; }
+  .key { fill: #b45309; font-size: 10px; }
+  .type { fill: #6b7280; }
+  .edge { fill: none; stroke: #64748b; stroke-width: 1.2; }
+  .marker { fill: #ffffff; stroke: #64748b; stroke-width: 1.2; }
+`
+
+func (g *svgGenerator) Generate(w io.Writer, s *schema.Schema) error {
+	out := newWriter(w)
+	d := layout.Compute(s, g.opts)
+
+	out.printf(`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s">`+"\n",
+		num(d.Width), num(d.Height), num(d.Width), num(d.Height))
+	out.printf("<title>%s</title>\n", xmlEscape(s.Database+" schema"))
+	out.printf("<style>%s</style>\n", svgStyle)
+	out.printf(`<rect width="%s" height="%s" fill="#ffffff"/>`+"\n", num(d.Width), num(d.Height))
+
+	out.print(`<g class="edges">` + "\n")
+	for _, e := range d.Edges {
+		writeSVGEdge(out, e)
+	}
+	out.print("</g>\n")
+
+	for _, b := range d.Boxes {
+		writeSVGBox(out, b)
+	}
+
+	out.print("</svg>\n")
+	return out.flush()
+}
+
+func writeSVGBox(out *writer, b layout.Box) {
+	class := "table"
+	switch {
+	case b.View:
+		class = "view"
+	case b.Collapsed:
+		class = "collapsed"
+	}
+
+	out.printf(`<g class="%s" id="%s">`+"\n", class, xmlEscape("table-"+b.Title))
+	out.printf(`  <rect class="box" x="%s" y="%s" width="%s" height="%s" rx="3"/>`+"\n",
+		num(b.X), num(b.Y), num(b.W), num(b.H))
+	out.printf(`  <rect class="header" x="%s" y="%s" width="%s" height="%s" rx="3"/>`+"\n",
+		num(b.X), num(b.Y), num(b.W), num(layout.HeaderSize))
+	out.printf(`  <text class="title" x="%s" y="%s">%s</text>`+"\n",
+		num(b.X+layout.Padding), num(b.Y+layout.HeaderSize-7), xmlEscape(b.Title))
+
+	nameX := b.X + layout.Padding + layout.TextWidth("PK ")
+	for i, row := range b.Rows {
+		y := b.Y + layout.HeaderSize + float64(i+1)*layout.RowHeight - 5
+		if row.Key != "" {
+			out.printf(`  <text class="key" x="%s" y="%s">%s</text>`+"\n", num(b.X+layout.Padding), num(y), row.Key)
+		}
+		out.printf(`  <text x="%s" y="%s">%s</text>`+"\n", num(nameX), num(y), xmlEscape(row.Name))
+		if row.Type != "" {
+			out.printf(`  <text class="type" x="%s" y="%s" text-anchor="end">%s</text>`+"\n",
+				num(b.X+b.W-layout.Padding), num(y), xmlEscape(row.Type))
+		}
+	}
+	if b.Collapsed {
+		y := b.Y + layout.HeaderSize + float64(len(b.Rows)+1)*layout.RowHeight - 5
+		out.printf(`  <text class="type" x="%s" y="%s">…</text>`+"\n", num(nameX), num(y))
+	}
+
+	out.print("</g>\n")
+}
+
+func writeSVGEdge(out *writer, e layout.Edge) {
+	fk := e.ForeignKey
+	out.printf(`<g class="relationship"><title>%s</title>`+"\n",
+		xmlEscape(fk.Table+"."+fk.Column+" → "+fk.ReferencedTable+"."+fk.ReferencedColumn))
+
+	out.printf(`  <path class="edge" d="%s"/>`+"\n", svgPath(e.Points...))
+
+	n := len(e.Points)
+	for _, m := range crowsFoot(e.Points[0], e.Points[1], true, e.Optional) {
+		writeSVGMark(out, m)
+	}
+	for _, m := range crowsFoot(e.Points[n-1], e.Points[n-2], false, false) {
+		writeSVGMark(out, m)
+	}
+
+	out.print("</g>\n")
+}
+
+func writeSVGMark(out *writer, m mark) {
+	if m.circle {
+		out.printf(`  <circle class="marker" cx="%s" cy="%s" r="%s"/>`+"\n", num(m.a.X), num(m.a.Y), num(m.r))
+		return
+	}
+	out.printf(`  <path class="edge" d="%s"/>`+"\n", svgPath(m.a, m.b))
+}
+
+// svgPath returns the path data of a polyline through points.
+func svgPath(points ...layout.Point) string {
+	var path strings.Builder
+	for i, p := range points {
+		if i == 0 {
+			path.WriteString("M")
+		} else {
+			path.WriteString(" L")
+		}
+		path.WriteString(num(p.X) + " " + num(p.Y))
+	}
+	return path.String()
+}
+
+// mark is a line from a to b, or a circle around a with radius r.
+type mark struct {
+	a, b   layout.Point
+	circle bool
+	r      float64
+}
+
+// crowsFoot returns the crow's-foot notation for the end of an edge at tip,
+// on a box border, whose first segment leads towards next. The many end
+// draws a foot, preceded by a circle when the relationship is optional or a
+// bar when it is mandatory; the one end draws two bars.
+func crowsFoot(tip, next layout.Point, many, optional bool) []mark {
+	dx, dy := next.X-tip.X, next.Y-tip.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return nil
+	}
+	ux, uy := dx/length, dy/length
+	nx, ny := -uy, ux
+
+	at := func(along, across float64) layout.Point {
+		return layout.Point{X: tip.X + ux*along + nx*across, Y: tip.Y + uy*along + ny*across}
+	}
+	bar := func(along float64) mark {
+		return mark{a: at(along, -6), b: at(along, 6)}
+	}
+
+	if !many {
+		return []mark{bar(6), bar(10)}
+	}
+
+	marks := []mark{
+		{a: at(12, 0), b: at(0, -7)},
+		{a: at(12, 0), b: at(0, 7)},
+	}
+	if optional {
+		marks = append(marks, mark{a: at(20, 0), circle: true, r: 4})
+	} else {
+		marks = append(marks, bar(16))
+	}
+	return marks
+}
+
+var xmlReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+
+func xmlEscape(s string) string {
+	return xmlReplacer.Replace(s)
+}
+
+// num formats a coordinate with at most one decimal.
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*10)/10, 'f', -1, 64)
+}
@@ -0,0 +1,114 @@
+package generators
+
+import (
+	"dbv/internal/layout"
+	"dbv/pkg/generator"
+	"strings"
+	"testing"
+)
+
+func TestSVGOptions(t *testing.T) {
+	for _, format := range []string{"svg", "html", "drawio"} {
+		if _, err := generator.New(format, generator.Options{"columns": "false", "types": "0"}); err != nil {
+			t.Errorf("%s: New() error = %v", format, err)
+		}
+		for _, opts := range []generator.Options{{"columns": "some"}, {"types": "2"}, {"scale": "2"}} {
+			if _, err := generator.New(format, opts); err == nil {
+				t.Errorf("%s: New(%v) returned no error", format, opts)
+			}
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	s := refSchema()
+	s.Tables[0].Name = `users <"&">`
+	s.ForeignKeys[0].ReferencedTable = s.Tables[0].Name
+	s.ForeignKeys[1].ReferencedTable = s.Tables[0].Name
+	out := generate(t, "svg", nil, s)
+
+	var doc struct {
+		Width  string `xml:"width,attr"`
+		Height string `xml:"height,attr"`
+		Title  string `xml:"title"`
+		Groups []struct {
+			Class string `xml:"class,attr"`
+			ID    string `xml:"id,attr"`
+			Texts []struct {
+				Class string `xml:"class,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"text"`
+			Relationships []struct {
+				Title string `xml:"title"`
+				Paths []struct {
+					D string `xml:"d,attr"`
+				} `xml:"path"`
+				Circles []struct{} `xml:"circle"`
+			} `xml:"g"`
+		} `xml:"g"`
+	}
+	decodeXML(t, out, &doc)
+
+	if doc.Title != "sqlite schema" || doc.Width == "" || doc.Height == "" {
+		t.Errorf("svg header = %q %sx%s", doc.Title, doc.Width, doc.Height)
+	}
+
+	tables := make(map[string][]string)
+	var relationships, circles int
+	for _, g := range doc.Groups {
+		if g.Class == "edges" {
+			for _, r := range g.Relationships {
+				relationships++
+				circles += len(r.Circles)
+				if len(r.Paths) < 2 || !strings.HasPrefix(r.Paths[0].D, "M") {
+					t.Errorf("relationship %s has paths %v", r.Title, r.Paths)
+				}
+			}
+			continue
+		}
+		for _, text := range g.Texts {
+			tables[g.ID] = append(tables[g.ID], text.Value)
+		}
+	}
+
+	if got := tables[`table-users <"&">`]; len(got) < 3 || got[0] != `users <"&">` || got[1] != "PK" || got[2] != "id" {
+		t.Errorf("users texts = %q", got)
+	}
+	if got := strings.Join(tables["table-orders"], " "); !strings.Contains(got, "FK user_id integer") {
+		t.Errorf("orders texts = %q", got)
+	}
+	// One relationship per column pair; only orders.user_id is optional.
+	if relationships != 4 || circles != 1 {
+		t.Errorf("relationships = %d with %d circles, want 4 with 1", relationships, circles)
+	}
+	if strings.Contains(out, "NaN") {
+		t.Error("output has NaN coordinates")
+	}
+}
+
+func TestSVGWithoutColumns(t *testing.T) {
+	out := generate(t, "svg", generator.Options{"columns": "false"}, refSchema())
+	if strings.Contains(out, ">user_id<") {
+		t.Errorf("output draws columns:\n%s", out)
+	}
+	d := layout.Compute(refSchema(), layout.Options{})
+	if !strings.Contains(out, `viewBox="0 0 `+num(d.Width)+" "+num(d.Height)+`"`) {
+		t.Errorf("viewBox does not match the layout of %vx%v", d.Width, d.Height)
+	}
+}
+
+func TestCrowsFoot(t *testing.T) {
+	tip, next := layout.Point{X: 10, Y: 10}, layout.Point{X: 10, Y: 50}
+	if marks := crowsFoot(tip, next, false, false); len(marks) != 2 {
+		t.Errorf("one end has %d marks, want two bars", len(marks))
+	}
+	if marks := crowsFoot(tip, next, true, false); len(marks) != 3 || marks[2].circle {
+		t.Errorf("mandatory many end = %+v, want a foot and a bar", marks)
+	}
+	if marks := crowsFoot(tip, next, true, true); len(marks) != 3 || !marks[2].circle {
+		t.Errorf("optional many end = %+v, want a foot and a circle", marks)
+	}
+	if marks := crowsFoot(tip, tip, true, true); marks != nil {
+		t.Errorf("zero-length segment has marks %+v", marks)
+	}
+}
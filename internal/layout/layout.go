@@ -0,0 +1,683 @@
+// Package layout places the tables and views of a schema.Schema on a plane
+// for the renderers that draw diagrams themselves (SVG, PDF, HTML). Tables are
+// arranged in layers so that referenced tables sit above the tables that
+// reference them, and relationships are routed as orthogonal polylines
+// through the gaps between layers.
+//
+// Text is measured for a monospaced font, where every character advances
+// CharWidth times the font size, so renderers must draw with one.
+package layout
+
+import (
+	"dbv/pkg/schema"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Metrics, in points at FontSize.
+const (
+	FontSize = 12.0
+	// CharWidth is the advance of a monospaced character, in ems.
+	CharWidth  = 0.6
+	RowHeight  = 18.0
+	HeaderSize = 24.0
+	Padding    = 8.0
+	Margin     = 24.0
+
+	boxGap      = 48.0
+	minLayerGap = 64.0
+	channelGap  = 14.0
+	// laneWidth is the room reserved in a layer for an edge passing through.
+	laneWidth = 12.0
+	loopSize  = 20.0
+)
+
+// Options control what the boxes show.
+type Options struct {
+	// Columns draws the columns of each table, not just its name.
+	Columns bool
+	// Types adds column types next to column names.
+	Types bool
+}
+
+// Diagram is a laid out schema. Coordinates grow right and down, in points,
+// from the top left corner.
+type Diagram struct {
+	Width, Height float64
+	Boxes         []Box
+	Edges         []Edge
+}
+
+// Box is a table or view.
+type Box struct {
+	Schema, Name string
+	// Title is the displayed, possibly schema-qualified, name.
+	Title      string
+	View       bool
+	Collapsed  bool
+	X, Y, W, H float64
+	Rows       []Row
+}
+
+// Row is one column of a box.
+type Row struct {
+	Name string
+	Type string
+	// Key is "PK", "FK" or empty.
+	Key      string
+	Nullable bool
+}
+
+// Edge is a relationship from the referencing (many) side to the referenced
+// (one) side. Points start on the border of the referencing box and end on
+// the border of the referenced one.
+type Edge struct {
+	ForeignKey schema.ForeignKey
+	// Optional is set when the referencing column is nullable.
+	Optional bool
+	Points   []Point
+}
+
+// Point is a position on the diagram.
+type Point struct {
+	X, Y float64
+}
+
+// TextWidth returns the width of s at FontSize.
+func TextWidth(s string) float64 {
+	return float64(len([]rune(s))) * CharWidth * FontSize
+}
+
+// ColumnType formats the type of a column for display, e.g. varchar(255).
+func ColumnType(col schema.Column) string {
+	t := strings.ToLower(col.Type)
+	switch {
+	case col.Length != nil && *col.Length > 0:
+		return fmt.Sprintf("%s(%d)", t, *col.Length)
+	case col.Precision != nil && col.Scale != nil:
+		return fmt.Sprintf("%s(%d,%d)", t, *col.Precision, *col.Scale)
+	}
+	return t
+}
+
+// Compute lays out s.
+func Compute(s *schema.Schema, opts Options) *Diagram {
+	d := &Diagram{}
+	index := boxes(d, s, opts)
+
+	var edges []edge
+	for _, fk := range s.ForeignKeys {
+		from, okFrom := index[nodeKey{fk.Schema, fk.Table}]
+		to, okTo := index[nodeKey{fk.ReferencedSchema, fk.ReferencedTable}]
+		if !okFrom || !okTo {
+			continue
+		}
+		edges = append(edges, edge{fk: fk, from: from, to: to, optional: nullable(s, fk)})
+	}
+
+	g := newGraph(d, len(s.Tables), edges)
+	g.order()
+	g.place()
+	d.Edges = g.route()
+
+	return d
+}
+
+type nodeKey struct{ schema, name string }
+
+// boxes adds a sized box for every table and view of s and returns the box
+// index of each table.
+func boxes(d *Diagram, s *schema.Schema, opts Options) map[nodeKey]int {
+	qualify := s.Qualifier()
+
+	foreignKeyColumns := make(map[nodeKey]map[string]bool)
+	for _, fk := range s.ForeignKeys {
+		k := nodeKey{fk.Schema, fk.Table}
+		if foreignKeyColumns[k] == nil {
+			foreignKeyColumns[k] = make(map[string]bool)
+		}
+		foreignKeyColumns[k][fk.Column] = true
+	}
+
+	rows := func(columns []schema.Column, key func(schema.Column) string) []Row {
+		if !opts.Columns {
+			return nil
+		}
+		result := make([]Row, 0, len(columns))
+		for _, col := range columns {
+			row := Row{Name: col.Name, Key: key(col), Nullable: col.IsNullable}
+			if opts.Types {
+				row.Type = ColumnType(col)
+			}
+			result = append(result, row)
+		}
+		return result
+	}
+
+	index := make(map[nodeKey]int)
+	for _, table := range s.Tables {
+		k := nodeKey{table.Schema, table.Name}
+		index[k] = len(d.Boxes)
+		d.Boxes = append(d.Boxes, Box{
+			Schema:    table.Schema,
+			Name:      table.Name,
+			Title:     qualify(table.Schema, table.Name),
+			Collapsed: table.Collapsed,
+			Rows: rows(table.Columns, func(col schema.Column) string {
+				switch {
+				case col.IsPrimaryKey || contains(table.PrimaryKeys, col.Name):
+					return "PK"
+				case foreignKeyColumns[k][col.Name]:
+					return "FK"
+				}
+				return ""
+			}),
+		})
+	}
+	for _, view := range s.Views {
+		d.Boxes = append(d.Boxes, Box{
+			Schema: view.Schema,
+			Name:   view.Name,
+			Title:  qualify(view.Schema, view.Name),
+			View:   true,
+			Rows:   rows(view.Columns, func(schema.Column) string { return "" }),
+		})
+	}
+
+	for i := range d.Boxes {
+		size(&d.Boxes[i])
+	}
+
+	return index
+}
+
+// size sets the width and height of a box from its rows.
+func size(b *Box) {
+	var name, typ float64
+	for _, row := range b.Rows {
+		name = math.Max(name, TextWidth(row.Name))
+		typ = math.Max(typ, TextWidth(row.Type))
+	}
+
+	w := TextWidth(b.Title) + 2*Padding
+	if len(b.Rows) > 0 {
+		rows := 2*Padding + TextWidth("PK ") + name
+		if typ > 0 {
+			rows += TextWidth("  ") + typ
+		}
+		w = math.Max(w, rows)
+	}
+
+	h := HeaderSize + float64(len(b.Rows))*RowHeight
+	if b.Collapsed {
+		h += RowHeight
+	}
+	if len(b.Rows) > 0 || b.Collapsed {
+		h += Padding / 2
+	}
+
+	b.W, b.H = math.Ceil(w), h
+}
+
+type edge struct {
+	fk       schema.ForeignKey
+	from, to int
+	optional bool
+}
+
+func nullable(s *schema.Schema, fk schema.ForeignKey) bool {
+	for _, table := range s.Tables {
+		if table.Schema != fk.Schema || table.Name != fk.Table {
+			continue
+		}
+		for _, col := range table.Columns {
+			if col.Name == fk.Column {
+				return col.IsNullable
+			}
+		}
+	}
+	return false
+}
+
+// graph is the layered graph of the related tables. Nodes are box indexes,
+// followed by lanes: placeholders that reserve room for an edge crossing a
+// layer, so that long edges never run through boxes.
+type graph struct {
+	d      *Diagram
+	edges  []edge
+	layers [][]int
+	// layer and x give the layer and left edge of each node; width of lanes
+	// is laneWidth.
+	layer map[int]int
+	x     map[int]float64
+	// stops lists, per edge, the nodes it passes through from the
+	// referencing box to the referenced one.
+	stops [][]int
+	// grid holds the boxes placed below the layers: unrelated tables and views.
+	grid  []int
+	gaps  []float64
+	lanes int
+}
+
+// newGraph assigns layers. Every related table sits one layer below the
+// deepest table it references, ignoring references that close a cycle.
+func newGraph(d *Diagram, tables int, edges []edge) *graph {
+	g := &graph{
+		d:     d,
+		edges: edges,
+		layer: make(map[int]int),
+		x:     make(map[int]float64),
+		stops: make([][]int, len(edges)),
+	}
+
+	parents := make(map[int][]int)
+	related := make(map[int]bool)
+	for _, e := range edges {
+		related[e.from], related[e.to] = true, true
+		if e.from != e.to {
+			parents[e.from] = append(parents[e.from], e.to)
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[int]int)
+	var visit func(n int)
+	visit = func(n int) {
+		state[n] = visiting
+		for _, p := range parents[n] {
+			switch state[p] {
+			case unvisited:
+				visit(p)
+			case visiting:
+				continue
+			}
+			g.layer[n] = max(g.layer[n], g.layer[p]+1)
+		}
+		state[n] = done
+	}
+
+	for n := range d.Boxes {
+		if n >= tables || !related[n] {
+			g.grid = append(g.grid, n)
+			continue
+		}
+		if state[n] == unvisited {
+			visit(n)
+		}
+		for len(g.layers) <= g.layer[n] {
+			g.layers = append(g.layers, nil)
+		}
+	}
+	for n := 0; n < tables; n++ {
+		if related[n] {
+			g.layers[g.layer[n]] = append(g.layers[g.layer[n]], n)
+		}
+	}
+
+	next := len(d.Boxes)
+	for i, e := range edges {
+		stops := []int{e.from}
+		from, to := g.layer[e.from], g.layer[e.to]
+		step := 1
+		if to < from {
+			step = -1
+		}
+		for l := from + step; l != to && from != to; l += step {
+			g.layer[next] = l
+			g.layers[l] = append(g.layers[l], next)
+			stops = append(stops, next)
+			next++
+		}
+		if e.from != e.to {
+			stops = append(stops, e.to)
+		}
+		g.stops[i] = stops
+	}
+	g.lanes = next - len(d.Boxes)
+
+	return g
+}
+
+func (g *graph) isLane(n int) bool {
+	return n >= len(g.d.Boxes)
+}
+
+func (g *graph) width(n int) float64 {
+	if g.isLane(n) {
+		return laneWidth
+	}
+	return g.d.Boxes[n].W
+}
+
+func (g *graph) center(n int) float64 {
+	return g.x[n] + g.width(n)/2
+}
+
+// order sorts each layer by the mean position of the connected nodes in the
+// layers above and below it, sweeping down and then up, which keeps related
+// tables close and removes most crossings.
+func (g *graph) order() {
+	neighbours := make(map[int][]int)
+	for _, stops := range g.stops {
+		for i := 1; i < len(stops); i++ {
+			a, b := stops[i-1], stops[i]
+			if a != b {
+				neighbours[a] = append(neighbours[a], b)
+				neighbours[b] = append(neighbours[b], a)
+			}
+		}
+	}
+
+	position := make(map[int]float64)
+	for _, layer := range g.layers {
+		for i, n := range layer {
+			position[n] = float64(i)
+		}
+	}
+
+	sweep := func(l, towards int) {
+		center := make(map[int]float64, len(g.layers[l]))
+		for _, n := range g.layers[l] {
+			var sum float64
+			var count int
+			for _, m := range neighbours[n] {
+				if g.layer[m] == towards {
+					sum += position[m]
+					count++
+				}
+			}
+			if count > 0 {
+				center[n] = sum / float64(count)
+			} else {
+				center[n] = position[n]
+			}
+		}
+		sort.SliceStable(g.layers[l], func(i, j int) bool {
+			return center[g.layers[l][i]] < center[g.layers[l][j]]
+		})
+		for i, n := range g.layers[l] {
+			position[n] = float64(i)
+		}
+	}
+
+	for round := 0; round < 4; round++ {
+		for l := 1; l < len(g.layers); l++ {
+			sweep(l, l-1)
+		}
+		for l := len(g.layers) - 2; l >= 0; l-- {
+			sweep(l, l+1)
+		}
+	}
+}
+
+// channel returns the gap a segment between two nodes runs in, identified
+// by the layer below the gap. Segments within a layer use the gap above it.
+func (g *graph) channel(a, b int) int {
+	return max(g.layer[a], g.layer[b])
+}
+
+// place assigns coordinates: layers top to bottom, each centered, with gaps
+// tall enough for the edge tracks routed through them, followed by a grid of
+// the unrelated tables and the views.
+func (g *graph) place() {
+	d := g.d
+
+	tracks := make([]int, len(g.layers)+1)
+	for _, stops := range g.stops {
+		for i := 1; i < len(stops); i++ {
+			tracks[g.channel(stops[i-1], stops[i])]++
+		}
+	}
+	g.gaps = make([]float64, len(g.layers)+1)
+	for l := range g.gaps {
+		g.gaps[l] = math.Max(minLayerGap, float64(tracks[l]+1)*channelGap)
+	}
+	if tracks[0] == 0 {
+		g.gaps[0] = 0
+	}
+
+	widths := make([]float64, len(g.layers))
+	var width float64
+	for l, layer := range g.layers {
+		for i, n := range layer {
+			if i > 0 {
+				widths[l] += gapBetween(g, layer[i-1], n)
+			}
+			widths[l] += g.width(n)
+		}
+		width = math.Max(width, widths[l])
+	}
+
+	y := Margin
+	for l, layer := range g.layers {
+		y += g.gaps[l]
+		x := Margin + (width-widths[l])/2
+		var height float64
+		for i, n := range layer {
+			if i > 0 {
+				x += gapBetween(g, layer[i-1], n)
+			}
+			g.x[n] = x
+			if !g.isLane(n) {
+				d.Boxes[n].X, d.Boxes[n].Y = x, y
+				height = math.Max(height, d.Boxes[n].H)
+			}
+			x += g.width(n)
+		}
+		y += height
+	}
+
+	if len(g.grid) > 0 {
+		perRow := int(math.Ceil(math.Sqrt(float64(len(g.grid)))))
+		if len(g.layers) > 0 {
+			// Fill the width of the layered part before adding rows.
+			var sum float64
+			for _, n := range g.grid {
+				sum += d.Boxes[n].W + boxGap
+			}
+			perRow = max(perRow, int(float64(len(g.grid))*width/sum))
+			y += minLayerGap
+		}
+
+		x := Margin
+		var height float64
+		for i, n := range g.grid {
+			if i > 0 && i%perRow == 0 {
+				x = Margin
+				y += height + boxGap
+				height = 0
+			}
+			d.Boxes[n].X, d.Boxes[n].Y = x, y
+			x += d.Boxes[n].W + boxGap
+			width = math.Max(width, x-boxGap-Margin)
+			height = math.Max(height, d.Boxes[n].H)
+		}
+		y += height
+	}
+
+	d.Width = math.Ceil(width + 2*Margin + loopSize)
+	d.Height = math.Ceil(y + Margin)
+}
+
+// gapBetween is the horizontal space between two neighbouring nodes; lanes
+// pack closer together than boxes.
+func gapBetween(g *graph, a, b int) float64 {
+	if g.isLane(a) || g.isLane(b) {
+		return boxGap / 4
+	}
+	return boxGap
+}
+
+// route draws every edge as an orthogonal polyline through its stops.
+// Edges leave and enter boxes through their top and bottom sides, spread out
+// along each side, and each segment runs horizontally on a track of its own
+// in the gap between two layers. Self-references loop around the right side
+// of their box.
+func (g *graph) route() []Edge {
+	d := g.d
+
+	type side struct {
+		box    int
+		bottom bool
+	}
+	// Segment k of edge i connects stops[k] and stops[k+1].
+	type segment struct{ edge, k int }
+
+	// sideOf returns the side of the box at stop k of edge i that faces
+	// its neighbour stop.
+	sideOf := func(i, k int) side {
+		stops := g.stops[i]
+		n := stops[k]
+		neighbour := stops[1]
+		if k > 0 {
+			neighbour = stops[k-1]
+		}
+		return side{n, g.layer[neighbour] > g.layer[n]}
+	}
+
+	// Spread the attachment points along each box side by the position of
+	// the neighbouring stop, so edges do not cross at the box.
+	attached := make(map[side][]segment)
+	for i, stops := range g.stops {
+		if len(stops) < 2 {
+			continue
+		}
+		last := len(stops) - 1
+		attached[sideOf(i, 0)] = append(attached[sideOf(i, 0)], segment{i, 0})
+		attached[sideOf(i, last)] = append(attached[sideOf(i, last)], segment{i, last})
+	}
+	anchor := make(map[segment]float64)
+	for s, list := range attached {
+		towards := func(seg segment) float64 {
+			stops := g.stops[seg.edge]
+			if seg.k == 0 {
+				return g.center(stops[1])
+			}
+			return g.center(stops[seg.k-1])
+		}
+		sort.SliceStable(list, func(a, b int) bool { return towards(list[a]) < towards(list[b]) })
+		box := d.Boxes[s.box]
+		for j, seg := range list {
+			anchor[seg] = box.X + box.W*float64(j+1)/float64(len(list)+1)
+		}
+	}
+
+	xAt := func(i, k int) float64 {
+		if x, ok := anchor[segment{i, k}]; ok {
+			return x
+		}
+		return g.center(g.stops[i][k])
+	}
+
+	// Give every segment its own track within its gap, ordered by their
+	// left ends so that parallel segments nest rather than cross.
+	byGap := make(map[int][]segment)
+	for i, stops := range g.stops {
+		for k := 0; k+1 < len(stops); k++ {
+			c := g.channel(stops[k], stops[k+1])
+			byGap[c] = append(byGap[c], segment{i, k})
+		}
+	}
+	track := make(map[segment]float64)
+	for c, list := range byGap {
+		top, bottom := g.gapBounds(c)
+		left := func(seg segment) float64 {
+			return math.Min(xAt(seg.edge, seg.k), xAt(seg.edge, seg.k+1))
+		}
+		sort.SliceStable(list, func(a, b int) bool { return left(list[a]) < left(list[b]) })
+		for j, seg := range list {
+			track[seg] = top + (bottom-top)*float64(j+1)/float64(len(list)+1)
+		}
+	}
+
+	result := make([]Edge, len(g.edges))
+	for i, e := range g.edges {
+		result[i] = Edge{ForeignKey: e.fk, Optional: e.optional}
+		stops := g.stops[i]
+
+		if len(stops) < 2 {
+			b := d.Boxes[e.from]
+			right := b.X + b.W
+			y1, y2 := b.Y+HeaderSize/2, b.Y+HeaderSize*3/4+b.H/4
+			result[i].Points = []Point{{right, y2}, {right + loopSize, y2}, {right + loopSize, y1}, {right, y1}}
+			continue
+		}
+
+		first, last := sideOf(i, 0), sideOf(i, len(stops)-1)
+		points := []Point{{xAt(i, 0), sideY(d.Boxes[first.box], first.bottom)}}
+		for k := 0; k+1 < len(stops); k++ {
+			y := track[segment{i, k}]
+			points = append(points, Point{xAt(i, k), y}, Point{xAt(i, k+1), y})
+		}
+		points = append(points, Point{xAt(i, len(stops)-1), sideY(d.Boxes[last.box], last.bottom)})
+
+		result[i].Points = simplify(points)
+	}
+
+	return result
+}
+
+func sideY(b Box, bottom bool) float64 {
+	if bottom {
+		return b.Y + b.H
+	}
+	return b.Y
+}
+
+// gapBounds returns the vertical extent of the gap above layer l.
+func (g *graph) gapBounds(l int) (float64, float64) {
+	bottom := math.Inf(1)
+	top := 0.0
+	for _, n := range g.layers[l] {
+		if !g.isLane(n) {
+			bottom = math.Min(bottom, g.d.Boxes[n].Y)
+		}
+	}
+	if l == 0 {
+		return bottom - g.gaps[0], bottom
+	}
+	for _, n := range g.layers[l-1] {
+		if !g.isLane(n) {
+			top = math.Max(top, g.d.Boxes[n].Y+g.d.Boxes[n].H)
+		}
+	}
+	if math.IsInf(bottom, 1) {
+		bottom = top + g.gaps[l]
+	}
+	return top, bottom
+}
+
+// simplify drops points that do not change the direction of a polyline.
+func simplify(points []Point) []Point {
+	result := points[:1]
+	for i := 1; i < len(points); i++ {
+		p := points[i]
+		if p == result[len(result)-1] {
+			continue
+		}
+		if n := len(result); n >= 2 {
+			a, b := result[n-2], result[n-1]
+			if (a.X == b.X && b.X == p.X) || (a.Y == b.Y && b.Y == p.Y) {
+				result[n-1] = p
+				continue
+			}
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package layout
+
+import (
+	"dbv/pkg/schema"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// layoutSchema has a chain of references, a cycle between teams and users,
+// a self-reference, an unrelated table and a view.
+func layoutSchema() *schema.Schema {
+	length := 255
+	table := func(name string, columns ...schema.Column) schema.Table {
+		id := schema.Column{Name: "id", Type: "INTEGER", IsPrimaryKey: true}
+		return schema.Table{Name: name, Schema: "public", PrimaryKeys: []string{"id"}, Columns: append([]schema.Column{id}, columns...)}
+	}
+	column := func(name string, nullable bool) schema.Column {
+		return schema.Column{Name: name, Type: "INTEGER", IsNullable: nullable}
+	}
+	fk := func(table, column, referenced string) schema.ForeignKey {
+		return schema.ForeignKey{Schema: "public", Table: table, Column: column,
+			ReferencedSchema: "public", ReferencedTable: referenced, ReferencedColumn: "id"}
+	}
+	return &schema.Schema{
+		Database: "postgresql",
+		Tables: []schema.Table{
+			table("orgs", schema.Column{Name: "name", Type: "VARCHAR", Length: &length}),
+			table("teams", column("org_id", false), column("lead_id", true)),
+			table("users", column("team_id", true)),
+			table("orders", column("user_id", false)),
+			table("order_items", column("order_id", false), column("product_id", false)),
+			table("products"),
+			table("employees", column("manager_id", true)),
+			table("settings", column("value", true)),
+		},
+		Views: []schema.View{
+			{Name: "active_users", Schema: "public", Columns: []schema.Column{column("id", false)}},
+		},
+		ForeignKeys: []schema.ForeignKey{
+			fk("teams", "org_id", "orgs"),
+			fk("teams", "lead_id", "users"),
+			fk("users", "team_id", "teams"),
+			fk("orders", "user_id", "users"),
+			fk("order_items", "order_id", "orders"),
+			fk("order_items", "product_id", "products"),
+			fk("employees", "manager_id", "employees"),
+			// Outside the schema: left out.
+			{Schema: "public", Table: "orders", Column: "user_id", ReferencedSchema: "audit", ReferencedTable: "log", ReferencedColumn: "id"},
+		},
+	}
+}
+
+func boxByName(t *testing.T, d *Diagram, name string) Box {
+	t.Helper()
+	for _, b := range d.Boxes {
+		if b.Name == name {
+			return b
+		}
+	}
+	t.Fatalf("no box for %s", name)
+	return Box{}
+}
+
+func onBorder(p Point, b Box) bool {
+	inX := p.X >= b.X-0.5 && p.X <= b.X+b.W+0.5
+	inY := p.Y >= b.Y-0.5 && p.Y <= b.Y+b.H+0.5
+	atX := math.Abs(p.X-b.X) < 0.5 || math.Abs(p.X-b.X-b.W) < 0.5
+	atY := math.Abs(p.Y-b.Y) < 0.5 || math.Abs(p.Y-b.Y-b.H) < 0.5
+	return (inX && atY) || (inY && atX)
+}
+
+func TestCompute(t *testing.T) {
+	d := Compute(layoutSchema(), Options{Columns: true, Types: true})
+
+	if len(d.Boxes) != 9 {
+		t.Fatalf("diagram has %d boxes, want 9", len(d.Boxes))
+	}
+
+	for i, a := range d.Boxes {
+		if a.X < Margin-0.5 || a.Y < Margin-0.5 || a.X+a.W > d.Width-Margin+0.5 || a.Y+a.H > d.Height-Margin+0.5 {
+			t.Errorf("box %s at %v,%v %vx%v is outside the %vx%v diagram", a.Name, a.X, a.Y, a.W, a.H, d.Width, d.Height)
+		}
+		for _, b := range d.Boxes[i+1:] {
+			if a.X < b.X+b.W && b.X < a.X+a.W && a.Y < b.Y+b.H && b.Y < a.Y+a.H {
+				t.Errorf("boxes %s and %s overlap", a.Name, b.Name)
+			}
+		}
+	}
+
+	// Referenced tables sit above the tables that reference them.
+	for _, pair := range [][2]string{{"orders", "users"}, {"order_items", "orders"}, {"order_items", "products"}, {"teams", "orgs"}} {
+		from, to := boxByName(t, d, pair[0]), boxByName(t, d, pair[1])
+		if from.Y <= to.Y {
+			t.Errorf("%s (y %v) is not below %s (y %v)", pair[0], from.Y, pair[1], to.Y)
+		}
+	}
+
+	if len(d.Edges) != 7 {
+		t.Errorf("diagram has %d edges, want 7", len(d.Edges))
+	}
+	for _, e := range d.Edges {
+		fk := e.ForeignKey
+		name := fmt.Sprintf("%s.%s", fk.Table, fk.Column)
+		if len(e.Points) < 2 {
+			t.Errorf("edge %s has %d points", name, len(e.Points))
+			continue
+		}
+		from, to := boxByName(t, d, fk.Table), boxByName(t, d, fk.ReferencedTable)
+		if first := e.Points[0]; !onBorder(first, from) {
+			t.Errorf("edge %s starts at %v, off the border of %s", name, first, fk.Table)
+		}
+		if last := e.Points[len(e.Points)-1]; !onBorder(last, to) {
+			t.Errorf("edge %s ends at %v, off the border of %s", name, last, fk.ReferencedTable)
+		}
+		for i := 1; i < len(e.Points); i++ {
+			a, b := e.Points[i-1], e.Points[i]
+			if a.X != b.X && a.Y != b.Y {
+				t.Errorf("edge %s has a diagonal segment %v-%v", name, a, b)
+			}
+		}
+		wantOptional := fk.Column == "lead_id" || fk.Column == "team_id" || fk.Column == "manager_id"
+		if e.Optional != wantOptional {
+			t.Errorf("edge %s optional = %v, want %v", name, e.Optional, wantOptional)
+		}
+	}
+}
+
+func TestComputeOptions(t *testing.T) {
+	rows := func(opts Options) []Row {
+		return boxByName(t, Compute(layoutSchema(), opts), "orgs").Rows
+	}
+
+	full := rows(Options{Columns: true, Types: true})
+	want := []Row{{Name: "id", Type: "integer", Key: "PK"}, {Name: "name", Type: "varchar(255)"}}
+	if !reflect.DeepEqual(full, want) {
+		t.Errorf("rows = %v, want %v", full, want)
+	}
+	if keys := boxByName(t, Compute(layoutSchema(), Options{Columns: true}), "orders").Rows; keys[1].Key != "FK" || keys[1].Type != "" {
+		t.Errorf("orders.user_id row = %+v, want an FK without type", keys[1])
+	}
+	if bare := rows(Options{}); len(bare) != 0 {
+		t.Errorf("rows without columns = %v", bare)
+	}
+
+	// Boxes fit their text.
+	for _, opts := range []Options{{}, {Columns: true}, {Columns: true, Types: true}} {
+		for _, b := range Compute(layoutSchema(), opts).Boxes {
+			if b.W < TextWidth(b.Title)+2*Padding || b.H != HeaderSize+float64(len(b.Rows))*RowHeight+padding(b) {
+				t.Errorf("box %s is %vx%v with %v", b.Name, b.W, b.H, opts)
+			}
+		}
+	}
+}
+
+func padding(b Box) float64 {
+	if len(b.Rows) > 0 {
+		return Padding / 2
+	}
+	return 0
+}
+
+func TestComputeEmpty(t *testing.T) {
+	d := Compute(&schema.Schema{}, Options{Columns: true})
+	if len(d.Boxes) != 0 || len(d.Edges) != 0 || d.Width < 0 || d.Height < 0 {
+		t.Errorf("empty diagram = %+v", d)
+	}
+}
+
+func TestColumnType(t *testing.T) {
+	length, precision, scale := 64, 10, 2
+	tests := []struct {
+		col  schema.Column
+		want string
+	}{
+		{schema.Column{Type: "INTEGER"}, "integer"},
+		{schema.Column{Type: "VARCHAR", Length: &length}, "varchar(64)"},
+		{schema.Column{Type: "numeric", Precision: &precision, Scale: &scale}, "numeric(10,2)"},
+		{schema.Column{Type: "numeric", Precision: &precision}, "numeric"},
+	}
+	for _, tt := range tests {
+		if got := ColumnType(tt.col); got != tt.want {
+			t.Errorf("ColumnType(%+v) = %q, want %q", tt.col, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package secrets
+
+import (
+	"net/url"
+	"os"
+	"strings"
+)
+
+// envStore reads secrets from environment variables. For the password it
+// first tries a variable specific to the connection,
+// DBV_PASSWORD_<HOST>_<DATABASE> with every non-alphanumeric character
+// replaced by an underscore, then DBV_PASSWORD; other secrets use their own
+// prefix, like DBV_SSLPASSWORD. Secrets cannot be saved; Set is a no-op.
+type envStore struct{}
+
+func (envStore) Get(u *url.URL, name string) (string, bool, error) {
+	prefix := "DBV_" + strings.ToUpper(name)
+	for _, variable := range []string{envName(prefix, u), prefix} {
+		if secret, ok := os.LookupEnv(variable); ok {
+			return secret, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func (envStore) Set(*url.URL, string, string) error {
+	return nil
+}
+
+func envName(prefix string, u *url.URL) string {
+	name := u.Hostname() + "_" + strings.TrimPrefix(u.Path, "/")
+	return prefix + "_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,165 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// fileStore keeps secrets in secrets.enc in dbv's config directory,
+// encrypted with AES-256-GCM. The key is derived from $DBV_SECRET_KEY when
+// set, otherwise it is a random key generated once into secrets.key. Both
+// files are only readable by the owner.
+type fileStore struct {
+	dir string
+}
+
+func newFileStore(dir string) fileStore {
+	return fileStore{dir: dir}
+}
+
+func (s fileStore) Get(u *url.URL, name string) (string, bool, error) {
+	passwords, err := s.load()
+	if err != nil {
+		return "", false, err
+	}
+	secret, ok := passwords[fileKey(u, name)]
+	return secret, ok, nil
+}
+
+func (s fileStore) Set(u *url.URL, name, secret string) error {
+	passwords, err := s.load()
+	if err != nil {
+		return err
+	}
+	passwords[fileKey(u, name)] = secret
+
+	plaintext, err := json.Marshal(passwords)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	return writeFile(filepath.Join(s.dir, "secrets.enc"), gcm.Seal(nonce, nonce, plaintext, nil))
+}
+
+// fileKey is the key of a secret in the file: the URL for the password and
+// the URL followed by the name for the others.
+func fileKey(u *url.URL, name string) string {
+	if name == Password {
+		return u.String()
+	}
+	return u.String() + " " + name
+}
+
+func (s fileStore) load() (map[string]string, error) {
+	passwords := make(map[string]string)
+
+	data, err := os.ReadFile(filepath.Join(s.dir, "secrets.enc"))
+	if errors.Is(err, os.ErrNotExist) {
+		return passwords, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("secrets file is corrupt")
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secrets file: %w", err)
+	}
+	if err := json.Unmarshal(plaintext, &passwords); err != nil {
+		return nil, err
+	}
+
+	return passwords, nil
+}
+
+func (s fileStore) cipher() (cipher.AEAD, error) {
+	key, err := s.key()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s fileStore) key() ([]byte, error) {
+	if passphrase := os.Getenv("DBV_SECRET_KEY"); passphrase != "" {
+		sum := sha256.Sum256([]byte(passphrase))
+		return sum[:], nil
+	}
+
+	path := filepath.Join(s.dir, "secrets.key")
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("secrets key %s is not 32 bytes", path)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := writeFile(path, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// writeFile replaces path atomically with data, readable only by the owner.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,65 @@
+package secrets
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	t.Setenv("DBV_SECRET_KEY", "")
+	dir := t.TempDir()
+	store := newFileStore(dir)
+
+	urls := []string{
+		"postgres://u:s3cret@h/db?sslpassword=key",
+		"postgres://v@h/db?password=other",
+	}
+	for _, databaseURL := range urls {
+		stripped, err := Save(store, databaseURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// A new store reads what the previous one wrote.
+		restored, err := Restore(newFileStore(dir), stripped)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, secrets := Strip(restored); len(secrets) == 0 {
+			t.Errorf("Restore(%q) = %q, without secrets", stripped, restored)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "secrets.enc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("s3cret")) {
+		t.Error("secrets.enc holds a password in plain text")
+	}
+	for _, name := range []string{"secrets.enc", "secrets.key"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		} else if info.Mode().Perm() != 0600 {
+			t.Errorf("%s mode = %v, want 0600", name, info.Mode().Perm())
+		}
+	}
+}
+
+func TestFileStorePassphrase(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DBV_SECRET_KEY", "one")
+	if _, err := Save(newFileStore(dir), "postgres://u:s3cret@h/db"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "secrets.key")); !os.IsNotExist(err) {
+		t.Error("a key file was written although DBV_SECRET_KEY is set")
+	}
+
+	t.Setenv("DBV_SECRET_KEY", "two")
+	if _, err := Restore(newFileStore(dir), "postgres://u@h/db"); err == nil {
+		t.Error("Restore with the wrong passphrase returned no error")
+	}
+}
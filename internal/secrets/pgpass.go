@@ -0,0 +1,151 @@
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pgpassStore reads and writes the libpq password file, $PGPASSFILE or
+// ~/.pgpass, whose lines are hostname:port:database:username:password.
+type pgpassStore struct {
+	path string
+}
+
+func newPgpassStore() pgpassStore {
+	if path := os.Getenv("PGPASSFILE"); path != "" {
+		return pgpassStore{path: path}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return pgpassStore{path: ".pgpass"}
+	}
+	return pgpassStore{path: filepath.Join(home, ".pgpass")}
+}
+
+// Get looks up the password of u. The file holds nothing else, so other
+// secrets are never found.
+func (s pgpassStore) Get(u *url.URL, name string) (string, bool, error) {
+	if name != Password {
+		return "", false, nil
+	}
+	password, ok, err := LookupPgpass(s.path, pgpassFields(u))
+	return password, ok, err
+}
+
+// Set replaces the entry that matches the connection exactly, or appends a
+// new one. The file is written with mode 0600, which libpq requires. Secrets
+// other than the password are not kept.
+func (s pgpassStore) Set(u *url.URL, name, password string) error {
+	if name != Password {
+		return nil
+	}
+	fields := pgpassFields(u)
+	entry := strings.Join(append(escapePgpass(fields[:]), escapePgpass([]string{password})...), ":")
+
+	data, err := os.ReadFile(s.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	var lines []string
+	replaced := false
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		if parts := splitPgpass(line); len(parts) == 5 && [4]string(parts[:4]) == fields {
+			line, replaced = entry, true
+		}
+		lines = append(lines, line)
+	}
+	if !replaced {
+		lines = append(lines, entry)
+	}
+
+	return writeFile(s.path, []byte(strings.Join(lines, "\n")+"\n"))
+}
+
+// LookupPgpass returns the password of the first line of the pgpass file at
+// path matching host, port, database and user, honouring * wildcards.
+func LookupPgpass(path string, fields [4]string) (string, bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := splitPgpass(line)
+		if len(parts) != 5 {
+			continue
+		}
+
+		match := true
+		for i, field := range fields {
+			if parts[i] != "*" && parts[i] != field {
+				match = false
+				break
+			}
+		}
+		if match {
+			return parts[4], true, nil
+		}
+	}
+
+	return "", false, scanner.Err()
+}
+
+// pgpassFields returns the host, port, database and user of u, with libpq's
+// defaults filled in.
+func pgpassFields(u *url.URL) [4]string {
+	host := u.Hostname()
+	if host == "" {
+		host = "localhost"
+	}
+	port := u.Port()
+	if port == "" {
+		port = "5432"
+	}
+	return [4]string{host, port, strings.TrimPrefix(u.Path, "/"), u.User.Username()}
+}
+
+// splitPgpass splits a pgpass line on unescaped colons and unescapes the
+// fields.
+func splitPgpass(line string) []string {
+	var fields []string
+	var field strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line):
+			i++
+			field.WriteByte(line[i])
+		case line[i] == ':':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(line[i])
+		}
+	}
+	return append(fields, field.String())
+}
+
+func escapePgpass(fields []string) []string {
+	escaped := make([]string, len(fields))
+	for i, field := range fields {
+		field = strings.ReplaceAll(field, `\`, `\\`)
+		escaped[i] = strings.ReplaceAll(field, ":", `\:`)
+	}
+	return escaped
+}
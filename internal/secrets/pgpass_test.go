@@ -0,0 +1,67 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPgpassStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pgpass")
+	if err := os.WriteFile(path, []byte("# comment\nother:5432:*:*:unrelated\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store := pgpassStore{path: path}
+
+	stripped, err := Save(store, "postgres://u:pa:ss@db:5433/app?sslpassword=key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Saving again replaces the entry instead of adding one.
+	if _, err := Save(store, "postgres://u:pa:ss@db:5433/app"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# comment\nother:5432:*:*:unrelated\ndb:5433:app:u:pa\\:ss\n"; string(data) != want {
+		t.Errorf("pgpass file = %q, want %q", data, want)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("pgpass file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// The file only holds passwords, so the key passphrase is not restored.
+	restored, err := Restore(store, stripped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "postgres://u:pa%3Ass@db:5433/app"; restored != want {
+		t.Errorf("Restore = %q, want %q", restored, want)
+	}
+}
+
+func TestLookupPgpassWildcards(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pgpass")
+	if err := os.WriteFile(path, []byte("db:*:app:*:first\n*:*:*:*:second\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		fields [4]string
+		want   string
+	}{
+		{[4]string{"db", "5432", "app", "u"}, "first"},
+		{[4]string{"db", "5432", "other", "u"}, "second"},
+	}
+	for _, tt := range tests {
+		got, ok, err := LookupPgpass(path, tt.fields)
+		if err != nil || !ok || got != tt.want {
+			t.Errorf("LookupPgpass(%v) = %q, %v, %v, want %q", tt.fields, got, ok, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Package secrets keeps database passwords out of dbv's connection history.
+// History entries are stored without passwords; a Store chosen by the user
+// remembers and restores them.
+package secrets
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Secret sources selectable in the configuration.
+const (
+	SourceNone   = "none"
+	SourceEnv    = "env"
+	SourcePgpass = "pgpass"
+	SourceFile   = "file"
+)
+
+// Sources lists the accepted secret sources.
+var Sources = []string{SourceNone, SourceEnv, SourcePgpass, SourceFile}
+
+// Names of the secrets a connection URL can carry: the password, in the
+// userinfo or as a query parameter, and the passphrase of the client key.
+const (
+	Password    = "password"
+	SSLPassword = "sslpassword"
+)
+
+// secretParams are the query parameters that hold secrets.
+var secretParams = []string{Password, SSLPassword}
+
+// Store remembers the secrets of a connection URL by name. The URL passed to
+// Get and Set never contains a secret.
+type Store interface {
+	Get(u *url.URL, name string) (secret string, ok bool, err error)
+	Set(u *url.URL, name, secret string) error
+}
+
+// New returns the store for source. dir is dbv's config directory, used by
+// stores that keep files of their own.
+func New(source, dir string) (Store, error) {
+	switch strings.ToLower(source) {
+	case "", SourceNone:
+		return noneStore{}, nil
+	case SourceEnv:
+		return envStore{}, nil
+	case SourcePgpass:
+		return newPgpassStore(), nil
+	case SourceFile:
+		return newFileStore(dir), nil
+	default:
+		return nil, fmt.Errorf("invalid secret source '%s'. Valid sources: %s", source, strings.Join(Sources, ", "))
+	}
+}
+
+// Strip splits databaseURL into the URL without its secrets and the
+// secrets, by name. A password in the userinfo wins over a password query
+// parameter.
+func Strip(databaseURL string) (stripped string, secrets map[string]string) {
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		return databaseURL, nil
+	}
+
+	secrets = make(map[string]string)
+	query := u.Query()
+	for _, name := range secretParams {
+		if query.Has(name) {
+			secrets[name] = query.Get(name)
+			query.Del(name)
+		}
+	}
+	if len(secrets) > 0 {
+		u.RawQuery = query.Encode()
+	}
+
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			secrets[Password] = password
+			u.User = url.User(u.User.Username())
+		}
+	}
+
+	if len(secrets) == 0 {
+		return databaseURL, nil
+	}
+	return u.String(), secrets
+}
+
+// Restore returns databaseURL with the secrets from store filled in. The
+// password goes to the userinfo when the URL names a user and to the query
+// otherwise. Secrets the URL already carries, or that are unknown, are left
+// as they are.
+func Restore(store Store, databaseURL string) (string, error) {
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		return databaseURL, nil
+	}
+
+	// Secrets were saved under the URL as it is now.
+	key := *u
+	query := u.Query()
+	changed := false
+	for _, name := range secretParams {
+		if query.Has(name) {
+			continue
+		}
+		if name == Password && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				continue
+			}
+		}
+
+		secret, ok, err := store.Get(&key, name)
+		if err != nil {
+			return databaseURL, err
+		}
+		if !ok {
+			continue
+		}
+
+		if name == Password && u.User != nil && u.User.Username() != "" {
+			u.User = url.UserPassword(u.User.Username(), secret)
+		} else {
+			query.Set(name, secret)
+		}
+		changed = true
+	}
+
+	if !changed {
+		return databaseURL, nil
+	}
+	if len(query) > 0 {
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), nil
+}
+
+// Mask returns databaseURL for display, with any password or other secret
+// replaced.
+func Mask(databaseURL string) string {
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		return databaseURL
+	}
+
+	query := u.Query()
+	masked := false
+	for _, name := range secretParams {
+		if query.Has(name) {
+			query.Set(name, "xxxxx")
+			masked = true
+		}
+	}
+	if masked {
+		u.RawQuery = query.Encode()
+	}
+	return u.Redacted()
+}
+
+// noneStore forgets passwords; they must be entered again.
+type noneStore struct{}
+
+func (noneStore) Get(*url.URL, string) (string, bool, error) { return "", false, nil }
+func (noneStore) Set(*url.URL, string, string) error         { return nil }
+
+// Save remembers the secrets of databaseURL in store and returns the URL
+// without them.
+func Save(store Store, databaseURL string) (string, error) {
+	stripped, secrets := Strip(databaseURL)
+	if len(secrets) == 0 {
+		return stripped, nil
+	}
+
+	u, err := url.Parse(stripped)
+	if err != nil {
+		return stripped, err
+	}
+	for _, name := range secretParams {
+		if secret, ok := secrets[name]; ok {
+			if err := store.Set(u, name, secret); err != nil {
+				return stripped, err
+			}
+		}
+	}
+	return stripped, nil
+}
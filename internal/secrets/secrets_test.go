@@ -0,0 +1,113 @@
+package secrets
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// mapStore is an in-memory Store.
+type mapStore map[string]string
+
+func (m mapStore) Get(u *url.URL, name string) (string, bool, error) {
+	secret, ok := m[name+" "+u.String()]
+	return secret, ok, nil
+}
+
+func (m mapStore) Set(u *url.URL, name, secret string) error {
+	m[name+" "+u.String()] = secret
+	return nil
+}
+
+func TestStrip(t *testing.T) {
+	tests := []struct {
+		url      string
+		stripped string
+		secrets  map[string]string
+	}{
+		{"postgres://u:s3cret@h/db", "postgres://u@h/db", map[string]string{Password: "s3cret"}},
+		{"postgres://u@h/db", "postgres://u@h/db", nil},
+		{"postgres://u@h/db?password=s3cret", "postgres://u@h/db", map[string]string{Password: "s3cret"}},
+		{
+			"postgres://u@h/db?sslmode=verify-full&sslpassword=key&password=s3cret",
+			"postgres://u@h/db?sslmode=verify-full",
+			map[string]string{Password: "s3cret", SSLPassword: "key"},
+		},
+		{"postgres://u:a@h/db?password=b", "postgres://u@h/db", map[string]string{Password: "a"}},
+		{"sqlite:///tmp/app.db", "sqlite:///tmp/app.db", nil},
+	}
+	for _, tt := range tests {
+		stripped, secrets := Strip(tt.url)
+		if stripped != tt.stripped {
+			t.Errorf("Strip(%q) URL = %q, want %q", tt.url, stripped, tt.stripped)
+		}
+		if !reflect.DeepEqual(secrets, tt.secrets) {
+			t.Errorf("Strip(%q) secrets = %v, want %v", tt.url, secrets, tt.secrets)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		url, want string
+	}{
+		{"postgres://u:s3cret@h/db", "postgres://u:xxxxx@h/db"},
+		{"postgres://u@h/db?password=s3cret&sslpassword=key", "postgres://u@h/db?password=xxxxx&sslpassword=xxxxx"},
+		{"postgres://u@h/db?sslmode=disable", "postgres://u@h/db?sslmode=disable"},
+	}
+	for _, tt := range tests {
+		if got := Mask(tt.url); got != tt.want {
+			t.Errorf("Mask(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestSaveRestore(t *testing.T) {
+	tests := []struct {
+		url      string
+		restored string
+	}{
+		{"postgres://u:s3cret@h/db", "postgres://u:s3cret@h/db"},
+		{"postgres://u@h/db?password=s3cret", "postgres://u:s3cret@h/db"},
+		{"postgres://h/db?password=s3cret", "postgres://h/db?password=s3cret"},
+		{"postgres://u@h/db?sslpassword=key&sslmode=require", "postgres://u@h/db?sslmode=require&sslpassword=key"},
+		{"postgres://u@h/db", "postgres://u@h/db"},
+	}
+	for _, tt := range tests {
+		store := mapStore{}
+		stripped, err := Save(store, tt.url)
+		if err != nil {
+			t.Fatalf("Save(%q) error = %v", tt.url, err)
+		}
+		if _, secrets := Strip(stripped); len(secrets) > 0 {
+			t.Errorf("Save(%q) = %q, which still has secrets", tt.url, stripped)
+		}
+
+		restored, err := Restore(store, stripped)
+		if err != nil {
+			t.Fatalf("Restore(%q) error = %v", stripped, err)
+		}
+		if restored != tt.restored {
+			t.Errorf("Restore(Save(%q)) = %q, want %q", tt.url, restored, tt.restored)
+		}
+	}
+}
+
+func TestRestoreKeepsGivenPassword(t *testing.T) {
+	store := mapStore{Password + " postgres://u@h/db": "stored"}
+	for _, databaseURL := range []string{"postgres://u:given@h/db", "postgres://u@h/db?password=given"} {
+		restored, err := Restore(store, databaseURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored != databaseURL {
+			t.Errorf("Restore(%q) = %q, want it unchanged", databaseURL, restored)
+		}
+	}
+}
+
+func TestNewInvalidSource(t *testing.T) {
+	if _, err := New("vault", t.TempDir()); err == nil {
+		t.Error("New with an unknown source returned no error")
+	}
+}
@@ -1,22 +1,67 @@
 package config
 
+// Config is the content of a dbv config file. Its top-level settings form
+// the default profile; a named profile overlays them key by key.
 type Config struct {
+    Profile        `mapstructure:",squash"`
+    DefaultProfile string             `mapstructure:"profile"`
+    Profiles       map[string]Profile `mapstructure:"profiles"`
+    History        HistoryConfig      `mapstructure:"history"`
+}
+
+// Profile is a named set of connection, schema and output settings, e.g. one
+// per environment of a database.
+type Profile struct {
     Database DatabaseConfig `mapstructure:"database"`
     Output   OutputConfig   `mapstructure:"output"`
     Schema   SchemaConfig   `mapstructure:"schema"`
+    Focus    FocusConfig    `mapstructure:"focus"`
+}
+
+// HistoryConfig controls the interactive connection history. Passwords are
+// never written to the history; SecretSource selects where they are kept:
+// none, env, pgpass or file.
+type HistoryConfig struct {
+    SecretSource string `mapstructure:"secret_source"`
 }
 
 type DatabaseConfig struct {
-    URL string `mapstructure:"url"`
+    URL string    `mapstructure:"url"`
+    SSH SSHConfig `mapstructure:"ssh"`
+}
+
+// SSHConfig describes an SSH tunnel to a database that is only reachable
+// through a bastion host. Hosts are written as [user@]host[:port].
+type SSHConfig struct {
+    Host       string `mapstructure:"host"`
+    User       string `mapstructure:"user"`
+    KeyFile    string `mapstructure:"key_file"`
+    KnownHosts string `mapstructure:"known_hosts"`
+    // Jump is an optional host the bastion itself is reached through.
+    Jump string `mapstructure:"jump"`
 }
 
 type OutputConfig struct {
     Format string `mapstructure:"format"`
     File   string `mapstructure:"file"`
+    // Options are format-specific settings, e.g. direction for d2.
+    Options map[string]string `mapstructure:"options"`
 }
 
 type SchemaConfig struct {
     IncludeViews   bool     `mapstructure:"include_views"`
     ExcludeTables  []string `mapstructure:"exclude_tables"`
     IncludeTables  []string `mapstructure:"include_tables"`
+    Schemas        []string `mapstructure:"schemas"`
+    Parallelism    int      `mapstructure:"parallelism"`
+    IncludeColumns []string `mapstructure:"include_columns"`
+    ExcludeColumns []string `mapstructure:"exclude_columns"`
+    Redaction      string   `mapstructure:"redaction"`
+}
+
+type FocusConfig struct {
+    Tables           []string `mapstructure:"tables"`
+    Depth            int      `mapstructure:"depth"`
+    Direction        string   `mapstructure:"direction"`
+    CollapseBoundary bool     `mapstructure:"collapse_boundary"`
 }
\ No newline at end of file
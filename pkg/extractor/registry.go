@@ -0,0 +1,140 @@
+// Package extractor is the registry that maps database URL schemes to
+// database/sql drivers and drivers to schema extractors. Backends register
+// themselves from an init function, so a module that blank-imports its own
+// extractor package can reuse dbv's CLI, filtering and generators:
+//
+//	func init() {
+//		extractor.RegisterScheme("mydb", "mydb", extractor.TrimScheme)
+//		extractor.Register("mydb", func(db *sql.DB, databaseURL string) (extractor.SchemaExtractor, error) {
+//			return &MyExtractor{db: db}, nil
+//		})
+//	}
+package extractor
+
+import (
+	"database/sql"
+	"dbv/pkg/config"
+	"dbv/pkg/schema"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SchemaExtractor reads the structure of an open database.
+type SchemaExtractor interface {
+	ExtractSchema(cfg config.SchemaConfig) (*schema.Schema, error)
+}
+
+// Factory creates a SchemaExtractor for a database opened from databaseURL.
+type Factory func(db *sql.DB, databaseURL string) (SchemaExtractor, error)
+
+// DSNFunc converts a parsed database URL into the data source name passed to
+// sql.Open. databaseURL is the original, unparsed URL.
+type DSNFunc func(u *url.URL, databaseURL string) (string, error)
+
+type scheme struct {
+	driver string
+	dsn    DSNFunc
+}
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+	schemes   = make(map[string]scheme)
+)
+
+// Register makes an extractor available for the named database/sql driver.
+// It panics if factory is nil or the driver already has an extractor.
+func Register(driver string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if factory == nil {
+		panic("extractor: Register factory is nil")
+	}
+	if _, dup := factories[driver]; dup {
+		panic("extractor: Register called twice for driver " + driver)
+	}
+	factories[driver] = factory
+}
+
+// RegisterScheme routes database URLs with the given scheme to driver, using
+// dsn to build the data source name. It panics if the scheme is taken.
+func RegisterScheme(urlScheme, driver string, dsn DSNFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	urlScheme = strings.ToLower(urlScheme)
+	if dsn == nil {
+		panic("extractor: RegisterScheme dsn is nil")
+	}
+	if _, dup := schemes[urlScheme]; dup {
+		panic("extractor: RegisterScheme called twice for scheme " + urlScheme)
+	}
+	schemes[urlScheme] = scheme{driver: driver, dsn: dsn}
+}
+
+// Resolve returns the driver name and data source name for databaseURL.
+func Resolve(databaseURL string) (driver, dsn string, err error) {
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	mu.RLock()
+	s, ok := schemes[strings.ToLower(u.Scheme)]
+	mu.RUnlock()
+	if !ok {
+		return "", "", fmt.Errorf("unsupported database scheme: %s", u.Scheme)
+	}
+
+	dsn, err = s.dsn(u, databaseURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	return s.driver, dsn, nil
+}
+
+// New creates the extractor registered for driver.
+func New(driver string, db *sql.DB, databaseURL string) (SchemaExtractor, error) {
+	mu.RLock()
+	factory, ok := factories[driver]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	return factory(db, databaseURL)
+}
+
+// Schemes returns the registered URL schemes in sorted order.
+func Schemes() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(schemes))
+	for name := range schemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// PassThrough is a DSNFunc that hands the URL to the driver unchanged.
+func PassThrough(_ *url.URL, databaseURL string) (string, error) {
+	return databaseURL, nil
+}
+
+// TrimScheme is a DSNFunc that strips the "scheme://" prefix, leaving a
+// file path or driver-specific DSN.
+func TrimScheme(u *url.URL, databaseURL string) (string, error) {
+	prefix := u.Scheme + "://"
+	if len(databaseURL) >= len(prefix) && strings.EqualFold(databaseURL[:len(prefix)], prefix) {
+		return databaseURL[len(prefix):], nil
+	}
+	return strings.TrimPrefix(databaseURL, u.Scheme+":"), nil
+}
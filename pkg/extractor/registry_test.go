@@ -0,0 +1,123 @@
+package extractor
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func nopFactory(*sql.DB, string) (SchemaExtractor, error) { return nil, nil }
+
+// expectPanic calls f and checks that it panics with a message containing
+// want.
+func expectPanic(t *testing.T, want string, f func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		r := recover()
+		if r == nil {
+			t.Fatalf("no panic, want one containing %q", want)
+		}
+		if msg, _ := r.(string); !strings.Contains(msg, want) {
+			t.Errorf("panic = %v, want one containing %q", r, want)
+		}
+	}()
+	f()
+}
+
+func TestRegisterPanics(t *testing.T) {
+	Register("test-register", nopFactory)
+
+	expectPanic(t, "called twice for driver test-register", func() {
+		Register("test-register", nopFactory)
+	})
+	expectPanic(t, "factory is nil", func() {
+		Register("test-register-nil", nil)
+	})
+}
+
+func TestRegisterSchemePanics(t *testing.T) {
+	RegisterScheme("test-scheme", "test-driver", PassThrough)
+
+	expectPanic(t, "called twice for scheme test-scheme", func() {
+		RegisterScheme("TEST-SCHEME", "other-driver", PassThrough)
+	})
+	expectPanic(t, "dsn is nil", func() {
+		RegisterScheme("test-scheme-nil", "test-driver", nil)
+	})
+}
+
+func TestResolve(t *testing.T) {
+	RegisterScheme("test-file", "test-file-driver", TrimScheme)
+	RegisterScheme("test-url", "test-url-driver", PassThrough)
+
+	tests := []struct {
+		url    string
+		driver string
+		dsn    string
+	}{
+		{"test-file:///var/db/app.db", "test-file-driver", "/var/db/app.db"},
+		{"TEST-FILE://app.db?mode=ro", "test-file-driver", "app.db?mode=ro"},
+		{"test-url://user@host/db", "test-url-driver", "test-url://user@host/db"},
+	}
+	for _, tt := range tests {
+		driver, dsn, err := Resolve(tt.url)
+		if err != nil {
+			t.Errorf("Resolve(%q) error = %v", tt.url, err)
+			continue
+		}
+		if driver != tt.driver || dsn != tt.dsn {
+			t.Errorf("Resolve(%q) = %q, %q, want %q, %q", tt.url, driver, dsn, tt.driver, tt.dsn)
+		}
+	}
+}
+
+func TestResolveUnknownScheme(t *testing.T) {
+	_, _, err := Resolve("nosuchdb://host/db")
+	if err == nil {
+		t.Fatal("Resolve() of an unknown scheme returned no error")
+	}
+	if want := "unsupported database scheme: nosuchdb"; err.Error() != want {
+		t.Errorf("Resolve() error = %q, want %q", err, want)
+	}
+
+	if _, err := New("nosuchdriver", nil, ""); err == nil || err.Error() != "unsupported database driver: nosuchdriver" {
+		t.Errorf("New() error = %v, want an unsupported driver error", err)
+	}
+}
+
+func TestTrimScheme(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"sqlite:///tmp/app.db", "/tmp/app.db"},
+		{"sqlite://app.db", "app.db"},
+		{"SQLITE://app.db", "app.db"},
+		{"sqlite:app.db", "app.db"},
+		{"duckdb://", ""},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := TrimScheme(u, tt.url)
+		if err != nil || got != tt.want {
+			t.Errorf("TrimScheme(%q) = %q, %v, want %q", tt.url, got, err, tt.want)
+		}
+	}
+}
+
+func TestSchemesSorted(t *testing.T) {
+	RegisterScheme("test-zz", "d", PassThrough)
+	RegisterScheme("test-aa", "d", PassThrough)
+
+	names := Schemes()
+	for i := 1; i < len(names); i++ {
+		if names[i-1] > names[i] {
+			t.Fatalf("Schemes() = %v, want sorted", names)
+		}
+	}
+}
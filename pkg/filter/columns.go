@@ -0,0 +1,210 @@
+package filter
+
+import (
+	"dbv/pkg/config"
+	"dbv/pkg/schema"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Redaction modes for columns matched by the column patterns.
+const (
+	// RedactHide drops matched columns.
+	RedactHide = "hide"
+	// RedactMask keeps matched columns but replaces their names, defaults
+	// and comments.
+	RedactMask = "mask"
+	// RedactCollapse replaces the matched columns of a table with a single
+	// placeholder column that only tells how many were hidden.
+	RedactCollapse = "collapse"
+)
+
+// ValidRedactions lists the accepted redaction modes.
+var ValidRedactions = []string{RedactHide, RedactMask, RedactCollapse}
+
+// Columns redacts the columns of every table and view in s according to the
+// IncludeColumns, ExcludeColumns and Redaction settings of cfg. Column
+// patterns use the same syntax as table patterns, qualified by table name
+// (users.email, *.created_at). A column is redacted when include patterns are
+// set and it matches none, or when it matches an exclude pattern. Primary key
+// and foreign key columns are never redacted, so relationships stay intact.
+func Columns(s *schema.Schema, cfg config.SchemaConfig) error {
+	if len(cfg.IncludeColumns) == 0 && len(cfg.ExcludeColumns) == 0 {
+		return nil
+	}
+
+	mode := strings.ToLower(cfg.Redaction)
+	if mode == "" {
+		mode = RedactHide
+	}
+	if mode != RedactHide && mode != RedactMask && mode != RedactCollapse {
+		return fmt.Errorf("invalid redaction '%s'. Valid modes: %s", cfg.Redaction, strings.Join(ValidRedactions, ", "))
+	}
+
+	include, err := CompileSet(cfg.IncludeColumns)
+	if err != nil {
+		return fmt.Errorf("include columns: %w", err)
+	}
+	exclude, err := CompileSet(cfg.ExcludeColumns)
+	if err != nil {
+		return fmt.Errorf("exclude columns: %w", err)
+	}
+
+	keys := keyColumns(s)
+	redacted := func(schemaName, table string, primaryKeys []string, col schema.Column) bool {
+		if col.IsPrimaryKey || keys[schemaName+"."+table+"."+col.Name] || slices.Contains(primaryKeys, col.Name) {
+			return false
+		}
+		if len(include) > 0 && !include.Match(table, col.Name) {
+			return true
+		}
+		return exclude.Match(table, col.Name)
+	}
+
+	for i := range s.Tables {
+		table := &s.Tables[i]
+		var renamed map[string]string
+		table.Columns, renamed = redactColumns(table.Columns, mode, func(col schema.Column) bool {
+			return redacted(table.Schema, table.Name, table.PrimaryKeys, col)
+		})
+		s.Indexes = redactIndexes(s.Indexes, table.Schema, table.Name, renamed)
+	}
+
+	for i := range s.Views {
+		view := &s.Views[i]
+		var renamed map[string]string
+		view.Columns, renamed = redactColumns(view.Columns, mode, func(col schema.Column) bool {
+			return redacted(view.Schema, view.Name, nil, col)
+		})
+		// The definition would reveal the redacted column names.
+		if len(renamed) > 0 {
+			view.Definition = ""
+		}
+	}
+
+	return nil
+}
+
+// redactColumns applies mode to the columns selected by match. It returns
+// the new column list and a map from each redacted column name to its new
+// name, or to "" when the column no longer exists.
+func redactColumns(columns []schema.Column, mode string, match func(schema.Column) bool) ([]schema.Column, map[string]string) {
+	renamed := make(map[string]string)
+	result := make([]schema.Column, 0, len(columns))
+	placeholder := -1
+
+	for _, col := range columns {
+		if !match(col) {
+			result = append(result, col)
+			continue
+		}
+
+		switch mode {
+		case RedactMask:
+			name := fmt.Sprintf("redacted_%d", len(renamed)+1)
+			renamed[col.Name] = name
+			col.Name = name
+			col.DefaultValue = nil
+			col.Comment = ""
+			result = append(result, col)
+		case RedactCollapse:
+			renamed[col.Name] = ""
+			if placeholder < 0 {
+				placeholder = len(result)
+				result = append(result, schema.Column{Type: "redacted", IsNullable: true})
+			}
+		default:
+			renamed[col.Name] = ""
+		}
+	}
+
+	if placeholder >= 0 {
+		result[placeholder].Name = fmt.Sprintf("redacted_%d_columns", len(renamed))
+	}
+
+	return result, renamed
+}
+
+// redactIndexes renames or drops redacted columns in the indexes of a table
+// and drops the indexes left without columns. Index names that mention a
+// redacted column, like idx_users_email, have the name replaced as well.
+func redactIndexes(indexes []schema.Index, schemaName, table string, renamed map[string]string) []schema.Index {
+	if len(renamed) == 0 {
+		return indexes
+	}
+
+	result := indexes[:0]
+	for _, index := range indexes {
+		if index.Schema != schemaName || index.Table != table {
+			result = append(result, index)
+			continue
+		}
+
+		columns := index.Columns[:0:0]
+		for _, col := range index.Columns {
+			name, ok := renamed[col]
+			if !ok {
+				name = col
+			}
+			if name != "" {
+				columns = append(columns, name)
+			}
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		index.Columns = columns
+
+		for col, name := range renamed {
+			if name == "" {
+				name = "redacted"
+			}
+			index.Name = replaceWord(index.Name, col, name)
+		}
+		result = append(result, index)
+	}
+	return result
+}
+
+// replaceWord replaces the case-insensitive occurrences of word in name that
+// are delimited by underscores, punctuation or the ends of name.
+func replaceWord(name, word, replacement string) string {
+	if word == "" {
+		return name
+	}
+	lower, word := strings.ToLower(name), strings.ToLower(word)
+	boundary := func(i int) bool {
+		if i < 0 || i >= len(name) {
+			return true
+		}
+		c := lower[i]
+		return !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9')
+	}
+
+	var b strings.Builder
+	last := 0
+	for i := 0; i+len(word) <= len(lower); {
+		if lower[i:i+len(word)] == word && boundary(i-1) && boundary(i+len(word)) {
+			b.WriteString(name[last:i])
+			b.WriteString(replacement)
+			i += len(word)
+			last = i
+			continue
+		}
+		i++
+	}
+	b.WriteString(name[last:])
+	return b.String()
+}
+
+// keyColumns returns the "schema.table.column" keys of every column used by a
+// foreign key, on either side.
+func keyColumns(s *schema.Schema) map[string]bool {
+	keys := make(map[string]bool)
+	for _, fk := range s.ForeignKeys {
+		keys[fk.Schema+"."+fk.Table+"."+fk.Column] = true
+		keys[fk.ReferencedSchema+"."+fk.ReferencedTable+"."+fk.ReferencedColumn] = true
+	}
+	return keys
+}
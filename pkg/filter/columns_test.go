@@ -0,0 +1,133 @@
+package filter
+
+import (
+	"dbv/pkg/config"
+	"dbv/pkg/schema"
+	"reflect"
+	"testing"
+)
+
+// redactionSchema has a users table in two schemas, where only the public
+// one has a phone column, so masked names differ between them. Each has an
+// index on email alone and public also one shared with a kept column.
+func redactionSchema() *schema.Schema {
+	users := func(schemaName string, extra ...schema.Column) schema.Table {
+		columns := []schema.Column{{Name: "id", Type: "integer", IsPrimaryKey: true}}
+		columns = append(columns, extra...)
+		columns = append(columns,
+			schema.Column{Name: "email", Type: "text", Comment: "login"},
+			schema.Column{Name: "name", Type: "text"},
+			schema.Column{Name: "team_id", Type: "integer"},
+		)
+		return schema.Table{Name: "users", Schema: schemaName, PrimaryKeys: []string{"id"}, Columns: columns}
+	}
+	index := func(schemaName, name string, columns ...string) schema.Index {
+		return schema.Index{Name: name, Schema: schemaName, Table: "users", Columns: columns}
+	}
+
+	return &schema.Schema{
+		Tables: []schema.Table{
+			users("public", schema.Column{Name: "phone", Type: "text"}),
+			users("audit"),
+		},
+		ForeignKeys: []schema.ForeignKey{{
+			Schema: "public", Table: "users", Column: "team_id",
+			ReferencedSchema: "public", ReferencedTable: "teams", ReferencedColumn: "id",
+		}},
+		Indexes: []schema.Index{
+			index("public", "idx_users_email", "email"),
+			index("public", "users_name_email_idx", "name", "email"),
+			index("audit", "idx_users_email", "email"),
+		},
+	}
+}
+
+func TestColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.SchemaConfig
+		public  []string
+		audit   []string
+		indexes []schema.Index
+	}{
+		{
+			name:   "hide",
+			cfg:    config.SchemaConfig{ExcludeColumns: []string{"users.phone", "users.email"}},
+			public: []string{"id", "name", "team_id"},
+			audit:  []string{"id", "name", "team_id"},
+			indexes: []schema.Index{
+				{Name: "users_name_redacted_idx", Schema: "public", Table: "users", Columns: []string{"name"}},
+			},
+		},
+		{
+			name:   "mask",
+			cfg:    config.SchemaConfig{ExcludeColumns: []string{"users.phone", "users.email"}, Redaction: "mask"},
+			public: []string{"id", "redacted_1", "redacted_2", "name", "team_id"},
+			audit:  []string{"id", "redacted_1", "name", "team_id"},
+			indexes: []schema.Index{
+				{Name: "idx_users_redacted_2", Schema: "public", Table: "users", Columns: []string{"redacted_2"}},
+				{Name: "users_name_redacted_2_idx", Schema: "public", Table: "users", Columns: []string{"name", "redacted_2"}},
+				{Name: "idx_users_redacted_1", Schema: "audit", Table: "users", Columns: []string{"redacted_1"}},
+			},
+		},
+		{
+			name:   "collapse",
+			cfg:    config.SchemaConfig{IncludeColumns: []string{"users.name"}, Redaction: "collapse"},
+			public: []string{"id", "redacted_2_columns", "name", "team_id"},
+			audit:  []string{"id", "redacted_2_columns", "name"},
+			indexes: []schema.Index{
+				{Name: "users_name_redacted_idx", Schema: "public", Table: "users", Columns: []string{"name"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := redactionSchema()
+			if err := Columns(s, tt.cfg); err != nil {
+				t.Fatalf("Columns() error = %v", err)
+			}
+
+			for i, want := range [][]string{tt.public, tt.audit} {
+				var columns []string
+				for _, col := range s.Tables[i].Columns {
+					columns = append(columns, col.Name)
+					if col.Comment != "" && col.Name != "email" {
+						t.Errorf("%s.users.%s kept the comment %q", s.Tables[i].Schema, col.Name, col.Comment)
+					}
+				}
+				if !reflect.DeepEqual(columns, want) {
+					t.Errorf("%s.users columns = %v, want %v", s.Tables[i].Schema, columns, want)
+				}
+			}
+			if !reflect.DeepEqual(s.Indexes, tt.indexes) {
+				t.Errorf("indexes = %+v, want %+v", s.Indexes, tt.indexes)
+			}
+		})
+	}
+}
+
+func TestColumnsInvalidRedaction(t *testing.T) {
+	err := Columns(redactionSchema(), config.SchemaConfig{ExcludeColumns: []string{"email"}, Redaction: "blur"})
+	if err == nil {
+		t.Fatal("Columns() with an unknown mode returned no error")
+	}
+}
+
+func TestReplaceWord(t *testing.T) {
+	tests := []struct {
+		name, word, replacement, want string
+	}{
+		{"idx_users_email", "email", "redacted_1", "idx_users_redacted_1"},
+		{"IDX_USERS_EMAIL", "email", "x", "IDX_USERS_x"},
+		{"idx_email_email", "email", "x", "idx_x_x"},
+		{"idx_emails", "email", "x", "idx_emails"},
+		{"idx_last_name", "last_name", "x", "idx_x"},
+		{"redacted", "redacted", "redacted_1", "redacted_1"},
+	}
+	for _, tt := range tests {
+		if got := replaceWord(tt.name, tt.word, tt.replacement); got != tt.want {
+			t.Errorf("replaceWord(%q, %q, %q) = %q, want %q", tt.name, tt.word, tt.replacement, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package filter matches database objects against include/exclude patterns.
+//
+// A pattern is one of:
+//
+//	orders          exact name, case-insensitive
+//	audit_*         glob using path.Match syntax (*, ?, [a-z]), case-insensitive
+//	billing.*       schema-qualified: the part before the first dot matches the
+//	                schema, the rest matches the name; both may be globs
+//	re:^tmp_\d+$    regular expression, tried against both "name" and
+//	                "schema.name"
+package filter
+
+import (
+	"dbv/pkg/config"
+	"dbv/pkg/schema"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const regexPrefix = "re:"
+
+// Pattern is a single compiled pattern.
+type Pattern struct {
+	raw       string
+	qualifier string
+	name      string
+	re        *regexp.Regexp
+}
+
+// Compile parses a pattern.
+func Compile(pattern string) (Pattern, error) {
+	p := Pattern{raw: pattern}
+
+	if expr, ok := strings.CutPrefix(pattern, regexPrefix); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return p, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		p.re = re
+		return p, nil
+	}
+
+	p.name = strings.ToLower(pattern)
+	if qualifier, name, ok := strings.Cut(p.name, "."); ok {
+		p.qualifier, p.name = qualifier, name
+	}
+
+	if _, err := path.Match(p.qualifier, ""); err != nil {
+		return p, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	if _, err := path.Match(p.name, ""); err != nil {
+		return p, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	return p, nil
+}
+
+// Match reports whether the object name in the given qualifier (a schema for
+// tables, a table for columns) matches the pattern.
+func (p Pattern) Match(qualifier, name string) bool {
+	if p.re != nil {
+		return p.re.MatchString(name) || (qualifier != "" && p.re.MatchString(qualifier+"."+name))
+	}
+
+	if p.qualifier != "" {
+		if ok, _ := path.Match(p.qualifier, strings.ToLower(qualifier)); !ok {
+			return false
+		}
+	}
+	ok, _ := path.Match(p.name, strings.ToLower(name))
+	return ok
+}
+
+func (p Pattern) String() string {
+	return p.raw
+}
+
+// Set matches if any of its patterns match.
+type Set []Pattern
+
+// CompileSet compiles every pattern, returning the first error.
+func CompileSet(patterns []string) (Set, error) {
+	set := make(Set, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		p, err := Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, p)
+	}
+	return set, nil
+}
+
+// Match reports whether any pattern in the set matches.
+func (s Set) Match(qualifier, name string) bool {
+	for _, p := range s {
+		if p.Match(qualifier, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tables decides which tables and views an extractor keeps, based on the
+// IncludeTables and ExcludeTables patterns of a config.SchemaConfig.
+type Tables struct {
+	include Set
+	exclude Set
+}
+
+// NewTables compiles the table patterns of cfg.
+func NewTables(cfg config.SchemaConfig) (*Tables, error) {
+	include, err := CompileSet(cfg.IncludeTables)
+	if err != nil {
+		return nil, fmt.Errorf("include tables: %w", err)
+	}
+	exclude, err := CompileSet(cfg.ExcludeTables)
+	if err != nil {
+		return nil, fmt.Errorf("exclude tables: %w", err)
+	}
+
+	return &Tables{include: include, exclude: exclude}, nil
+}
+
+// Allow reports whether the table or view should be extracted. With include
+// patterns set, only matching objects are kept; exclusions always win.
+func (t *Tables) Allow(schemaName, name string) bool {
+	if len(t.include) > 0 && !t.include.Match(schemaName, name) {
+		return false
+	}
+	return !t.exclude.Match(schemaName, name)
+}
+
+// AllowForeignKey reports whether a relationship should be extracted. It is
+// kept when either end is included and neither end is excluded.
+func (t *Tables) AllowForeignKey(fk schema.ForeignKey) bool {
+	if len(t.include) > 0 && !t.include.Match(fk.Schema, fk.Table) && !t.include.Match(fk.ReferencedSchema, fk.ReferencedTable) {
+		return false
+	}
+	return !t.exclude.Match(fk.Schema, fk.Table) && !t.exclude.Match(fk.ReferencedSchema, fk.ReferencedTable)
+}
@@ -0,0 +1,143 @@
+package filter
+
+import (
+	"dbv/pkg/config"
+	"dbv/pkg/schema"
+	"strings"
+	"testing"
+)
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		qualifier string
+		name      string
+		want      bool
+	}{
+		{"orders", "public", "orders", true},
+		{"orders", "public", "ORDERS", true},
+		{"Orders", "public", "orders", true},
+		{"orders", "public", "orders_archive", false},
+		{"audit_*", "public", "audit_log", true},
+		{"audit_*", "public", "audits", false},
+		{"order?", "public", "orders", true},
+		{"[a-c]*", "public", "billing", true},
+		{"[a-c]*", "public", "users", false},
+		{"billing.*", "billing", "invoices", true},
+		{"billing.*", "public", "invoices", false},
+		{"b*.inv*", "billing", "invoices", true},
+		{"public.users", "public", "users", true},
+		{"public.users", "audit", "users", false},
+		{"users.email", "users", "email", true},
+		{"re:^tmp_\\d+$", "public", "tmp_42", true},
+		{"re:^tmp_\\d+$", "public", "tmp_x", false},
+		{"re:^audit\\.", "audit", "log", true},
+		{"re:^audit\\.", "", "log", false},
+	}
+	for _, tt := range tests {
+		p, err := Compile(tt.pattern)
+		if err != nil {
+			t.Fatalf("Compile(%q) error = %v", tt.pattern, err)
+		}
+		if got := p.Match(tt.qualifier, tt.name); got != tt.want {
+			t.Errorf("%q.Match(%q, %q) = %v, want %v", tt.pattern, tt.qualifier, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, pattern := range []string{"re:(", "re:[a-", "users[", "bad[.users"} {
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("Compile(%q) returned no error", pattern)
+		}
+	}
+	if _, err := NewTables(config.SchemaConfig{ExcludeTables: []string{"ok", "re:("}}); err == nil {
+		t.Error("NewTables with an invalid pattern returned no error")
+	}
+}
+
+func TestCompileSetSkipsEmpty(t *testing.T) {
+	set, err := CompileSet([]string{"", "users", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set) != 1 || !set.Match("public", "users") || set.Match("public", "orders") {
+		t.Errorf("CompileSet = %v, want only users", set)
+	}
+}
+
+func TestTablesAllow(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		allowed map[string]bool
+	}{
+		{
+			name:    "no patterns",
+			allowed: map[string]bool{"public.users": true, "audit.log": true},
+		},
+		{
+			name:    "include",
+			include: []string{"users", "audit.*"},
+			allowed: map[string]bool{"public.users": true, "public.orders": false, "audit.log": true},
+		},
+		{
+			name:    "exclude",
+			exclude: []string{"re:_archive$"},
+			allowed: map[string]bool{"public.orders": true, "public.orders_archive": false},
+		},
+		{
+			name:    "exclude wins over include",
+			include: []string{"orders*"},
+			exclude: []string{"orders_archive"},
+			allowed: map[string]bool{"public.orders": true, "public.orders_archive": false, "public.users": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewTables(config.SchemaConfig{IncludeTables: tt.include, ExcludeTables: tt.exclude})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for table, want := range tt.allowed {
+				schemaName, name, _ := strings.Cut(table, ".")
+				if got := f.Allow(schemaName, name); got != want {
+					t.Errorf("Allow(%s) = %v, want %v", table, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTablesAllowForeignKey(t *testing.T) {
+	fk := schema.ForeignKey{
+		Schema: "public", Table: "orders", Column: "user_id",
+		ReferencedSchema: "public", ReferencedTable: "users", ReferencedColumn: "id",
+	}
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    bool
+	}{
+		{"no patterns", nil, nil, true},
+		{"source included", []string{"orders"}, nil, true},
+		{"target included", []string{"users"}, nil, true},
+		{"neither included", []string{"products"}, nil, false},
+		{"source excluded", nil, []string{"orders"}, false},
+		{"target excluded", nil, []string{"users"}, false},
+		{"included but target excluded", []string{"orders", "users"}, []string{"public.users"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewTables(config.SchemaConfig{IncludeTables: tt.include, ExcludeTables: tt.exclude})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.AllowForeignKey(fk); got != tt.want {
+				t.Errorf("AllowForeignKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Package generator is the registry of output formats. Formats register a
+// factory from an init function, so a module that blank-imports its own
+// generator package gets the format in dbv's flags, config and interactive
+// form:
+//
+//	func init() {
+//		generator.Register("myformat", func(opts generator.Options) (generator.Generator, error) {
+//			return &MyGenerator{}, nil
+//		})
+//	}
+package generator
+
+import (
+	"dbv/pkg/schema"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Generator renders a schema in one output format.
+type Generator interface {
+	// Name is the format name used by --format, e.g. "mermaid".
+	Name() string
+	// Description is a short human-readable label for the format.
+	Description() string
+	// Extension is the default output file extension, including the dot.
+	Extension() string
+	// Options lists the settings accepted through Options.
+	Options() []Option
+	// Generate writes the rendered schema to w.
+	Generate(w io.Writer, s *schema.Schema) error
+}
+
+// FileCreator creates one file of a multi-file output. name is a
+// slash-separated path relative to the output directory. The files are put
+// in place together once GenerateFiles succeeds.
+type FileCreator func(name string) (io.WriteCloser, error)
+
+// MultiFileGenerator is implemented by formats that write a directory of
+// files, such as a documentation site, rather than a single document. The
+// output path names the directory and GenerateFiles is used instead of
+// Generate.
+type MultiFileGenerator interface {
+	Generator
+	// GenerateFiles writes the rendered schema as files made with create.
+	GenerateFiles(create FileCreator, s *schema.Schema) error
+}
+
+// Option describes a format-specific setting, passed as --format-opt
+// name=value or under output.options in the config file.
+type Option struct {
+	Name        string
+	Description string
+	Default     string
+}
+
+// Options are the format-specific settings chosen by the user, by name.
+type Options map[string]string
+
+// String returns the named option, or def when it is unset.
+func (o Options) String(name, def string) string {
+	if value, ok := o[name]; ok && value != "" {
+		return value
+	}
+	return def
+}
+
+// Bool returns the named option parsed as a boolean, or def when it is unset.
+func (o Options) Bool(name string, def bool) (bool, error) {
+	value, ok := o[name]
+	if !ok || value == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("option %s: invalid boolean '%s'", name, value)
+	}
+	return b, nil
+}
+
+// Factory creates a Generator configured with opts.
+type Factory func(opts Options) (Generator, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes a format available under name. It panics if factory is nil
+// or the name is already registered.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if factory == nil {
+		panic("generator: Register factory is nil")
+	}
+	if _, dup := factories[name]; dup {
+		panic("generator: Register called twice for format " + name)
+	}
+	factories[name] = factory
+}
+
+// New creates the generator registered as name. Options the format does not
+// declare are rejected.
+func New(name string, opts Options) (Generator, error) {
+	mu.RLock()
+	factory, ok := factories[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("invalid format '%s'. Valid formats: %s", name, strings.Join(Names(), ", "))
+	}
+
+	g, err := factory(opts)
+	if err != nil {
+		return nil, fmt.Errorf("format %s: %w", name, err)
+	}
+
+	for key := range opts {
+		if !declares(g, key) {
+			return nil, fmt.Errorf("unknown option '%s' for format %s. Valid options: %s",
+				key, name, strings.Join(optionNames(g), ", "))
+		}
+	}
+
+	return g, nil
+}
+
+// Names returns the registered format names in sorted order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func declares(g Generator, name string) bool {
+	for _, opt := range g.Options() {
+		if opt.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func optionNames(g Generator) []string {
+	names := make([]string, 0)
+	for _, opt := range g.Options() {
+		names = append(names, opt.Name)
+	}
+	if len(names) == 0 {
+		return []string{"none"}
+	}
+	return names
+}
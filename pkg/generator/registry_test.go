@@ -0,0 +1,159 @@
+package generator
+
+import (
+	"dbv/pkg/schema"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testGenerator declares the options named in its options field.
+type testGenerator struct {
+	options []Option
+}
+
+func (g *testGenerator) Name() string                             { return "test" }
+func (g *testGenerator) Description() string                      { return "Test" }
+func (g *testGenerator) Extension() string                        { return ".txt" }
+func (g *testGenerator) Options() []Option                        { return g.options }
+func (g *testGenerator) Generate(io.Writer, *schema.Schema) error { return nil }
+
+func init() {
+	Register("test-plain", func(Options) (Generator, error) {
+		return &testGenerator{}, nil
+	})
+	Register("test-options", func(opts Options) (Generator, error) {
+		if _, err := opts.Bool("strict", false); err != nil {
+			return nil, err
+		}
+		return &testGenerator{options: []Option{
+			{Name: "strict", Default: "false"},
+			{Name: "style", Default: "plain"},
+		}}, nil
+	})
+	Register("test-broken", func(Options) (Generator, error) {
+		return nil, errors.New("broken")
+	})
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		opts    Options
+		wantErr string
+	}{
+		{name: "no options", format: "test-plain"},
+		{name: "declared options", format: "test-options", opts: Options{"strict": "true", "style": "bold"}},
+		{
+			name: "unknown format", format: "nosuch",
+			wantErr: "invalid format 'nosuch'. Valid formats: ",
+		},
+		{
+			name: "unknown option", format: "test-options", opts: Options{"style": "bold", "colour": "red"},
+			wantErr: "unknown option 'colour' for format test-options. Valid options: strict, style",
+		},
+		{
+			name: "format without options", format: "test-plain", opts: Options{"style": "bold"},
+			wantErr: "unknown option 'style' for format test-plain. Valid options: none",
+		},
+		{
+			name: "invalid value", format: "test-options", opts: Options{"strict": "maybe"},
+			wantErr: "format test-options: option strict: invalid boolean 'maybe'",
+		},
+		{
+			name: "factory error", format: "test-broken",
+			wantErr: "format test-broken: broken",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := New(tt.format, tt.opts)
+			if tt.wantErr == "" {
+				if err != nil || g == nil {
+					t.Fatalf("New() = %v, %v", g, err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// The unknown format error lists the registered formats.
+	if _, err := New("nosuch", nil); err == nil || !strings.Contains(err.Error(), "test-options, test-plain") {
+		t.Errorf("New() error = %v, want it to list the formats", err)
+	}
+}
+
+func TestOptionsString(t *testing.T) {
+	opts := Options{"style": "bold", "empty": ""}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"style", "bold"},
+		{"empty", "default"},
+		{"missing", "default"},
+	}
+	for _, tt := range tests {
+		if got := opts.String(tt.name, "default"); got != tt.want {
+			t.Errorf("String(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := Options(nil).String("style", "plain"); got != "plain" {
+		t.Errorf("nil Options String() = %q, want plain", got)
+	}
+}
+
+func TestOptionsBool(t *testing.T) {
+	opts := Options{"yes": "true", "no": "0", "upper": "FALSE", "empty": "", "bad": "maybe"}
+	tests := []struct {
+		name    string
+		def     bool
+		want    bool
+		wantErr bool
+	}{
+		{"yes", false, true, false},
+		{"no", true, false, false},
+		{"upper", true, false, false},
+		{"empty", true, true, false},
+		{"missing", true, true, false},
+		{"missing", false, false, false},
+		{"bad", true, false, true},
+	}
+	for _, tt := range tests {
+		got, err := opts.Bool(tt.name, tt.def)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Bool(%q, %v) = %v, %v, want %v (error %v)", tt.name, tt.def, got, err, tt.want, tt.wantErr)
+		}
+	}
+	if _, err := opts.Bool("bad", false); err == nil || err.Error() != "option bad: invalid boolean 'maybe'" {
+		t.Errorf("Bool() error = %v", err)
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		factory Factory
+		want    string
+	}{
+		{"duplicate", "test-plain", func(Options) (Generator, error) { return nil, nil }, "called twice for format test-plain"},
+		{"nil factory", "test-nil", nil, "factory is nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if msg, _ := r.(string); !strings.Contains(msg, tt.want) {
+					t.Errorf("panic = %v, want one containing %q", r, tt.want)
+				}
+			}()
+			Register(tt.format, tt.factory)
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Direction selects which foreign keys a focus walk follows.
+type Direction string
+
+const (
+	// Both follows references in either direction.
+	Both Direction = "both"
+	// Outgoing follows references from a table to the tables it points at.
+	Outgoing Direction = "out"
+	// Incoming follows references from a table to the tables pointing at it.
+	Incoming Direction = "in"
+)
+
+// ParseDirection validates a direction name. An empty name means Both.
+func ParseDirection(name string) (Direction, error) {
+	switch d := Direction(strings.ToLower(name)); d {
+	case "":
+		return Both, nil
+	case Both, Outgoing, Incoming:
+		return d, nil
+	default:
+		return "", fmt.Errorf("invalid focus direction '%s'. Valid directions: both, out, in", name)
+	}
+}
+
+// FocusOptions describes the neighbourhood kept by Focus.
+type FocusOptions struct {
+	// Tables are the starting tables or views, as "name" or "schema.name".
+	Tables []string
+	// Depth is the number of foreign-key hops to walk from the start tables.
+	Depth int
+	// Direction selects which references are followed.
+	Direction Direction
+	// CollapseBoundary reduces the tables on the edge of the neighbourhood,
+	// whose references were cut off, to their key columns.
+	CollapseBoundary bool
+}
+
+// Focus returns the subgraph of s within opts.Depth foreign-key hops of the
+// start tables. Only relationships between kept tables remain. Views are kept
+// only when they are start objects, since they have no foreign keys.
+func (s *Schema) Focus(opts FocusOptions) (*Schema, error) {
+	type key struct{ schema, name string }
+
+	known := make(map[key]bool, len(s.Tables))
+	for _, table := range s.Tables {
+		known[key{table.Schema, table.Name}] = true
+	}
+
+	start := make(map[key]bool)
+	for _, name := range opts.Tables {
+		found := false
+		for _, table := range s.Tables {
+			if matchesName(table.Schema, table.Name, name) {
+				start[key{table.Schema, table.Name}] = true
+				found = true
+			}
+		}
+		for _, view := range s.Views {
+			if matchesName(view.Schema, view.Name, name) {
+				start[key{view.Schema, view.Name}] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("focus table '%s' not found in schema", name)
+		}
+	}
+
+	neighbours := make(map[key][]key)
+	for _, fk := range s.ForeignKeys {
+		from := key{fk.Schema, fk.Table}
+		to := key{fk.ReferencedSchema, fk.ReferencedTable}
+		if opts.Direction != Incoming {
+			neighbours[from] = append(neighbours[from], to)
+		}
+		if opts.Direction != Outgoing {
+			neighbours[to] = append(neighbours[to], from)
+		}
+	}
+
+	distance := make(map[key]int, len(start))
+	queue := make([]key, 0, len(start))
+	for k := range start {
+		distance[k] = 0
+		queue = append(queue, k)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if distance[current] == opts.Depth {
+			continue
+		}
+		for _, next := range neighbours[current] {
+			if _, seen := distance[next]; seen || !known[next] {
+				continue
+			}
+			distance[next] = distance[current] + 1
+			queue = append(queue, next)
+		}
+	}
+
+	focused := &Schema{
+		Database:    s.Database,
+		Enums:       s.Enums,
+		GeneratedAt: s.GeneratedAt,
+	}
+
+	for _, fk := range s.ForeignKeys {
+		_, fromKept := distance[key{fk.Schema, fk.Table}]
+		_, toKept := distance[key{fk.ReferencedSchema, fk.ReferencedTable}]
+		if fromKept && toKept {
+			focused.ForeignKeys = append(focused.ForeignKeys, fk)
+		}
+	}
+
+	for _, table := range s.Tables {
+		k := key{table.Schema, table.Name}
+		d, kept := distance[k]
+		if !kept {
+			continue
+		}
+
+		if opts.CollapseBoundary && d > 0 && d == opts.Depth {
+			for _, next := range neighbours[k] {
+				if _, inside := distance[next]; !inside {
+					table = collapse(table, focused.ForeignKeys)
+					break
+				}
+			}
+		}
+		focused.Tables = append(focused.Tables, table)
+	}
+
+	for _, view := range s.Views {
+		if start[key{view.Schema, view.Name}] {
+			focused.Views = append(focused.Views, view)
+		}
+	}
+
+	for _, index := range s.Indexes {
+		for _, table := range focused.Tables {
+			if index.Table == table.Name && (index.Schema == "" || index.Schema == table.Schema) {
+				focused.Indexes = append(focused.Indexes, index)
+				break
+			}
+		}
+	}
+
+	return focused, nil
+}
+
+// collapse keeps only the primary key columns of table and the columns used by
+// the given relationships.
+func collapse(table Table, foreignKeys []ForeignKey) Table {
+	keys := make(map[string]bool)
+	for _, fk := range foreignKeys {
+		if fk.Schema == table.Schema && fk.Table == table.Name {
+			keys[fk.Column] = true
+		}
+		if fk.ReferencedSchema == table.Schema && fk.ReferencedTable == table.Name {
+			keys[fk.ReferencedColumn] = true
+		}
+	}
+
+	columns := make([]Column, 0, len(table.PrimaryKeys)+len(keys))
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey || keys[col.Name] || containsFold(table.PrimaryKeys, col.Name) {
+			columns = append(columns, col)
+		}
+	}
+
+	table.Columns = columns
+	table.Collapsed = true
+	return table
+}
+
+// matchesName reports whether name refers to the object, either bare or
+// qualified with its schema. Names compare case-insensitively.
+func matchesName(schemaName, objectName, name string) bool {
+	if strings.EqualFold(objectName, name) {
+		return true
+	}
+	return schemaName != "" && strings.EqualFold(schemaName+"."+objectName, name)
+}
+
+func containsFold(slice []string, item string) bool {
+	for _, s := range slice {
+		if strings.EqualFold(s, item) {
+			return true
+		}
+	}
+	return false
+}
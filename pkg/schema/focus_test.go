@@ -0,0 +1,223 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func fk(table, column, referenced string) ForeignKey {
+	return ForeignKey{
+		Name: table + "_" + column + "_fk", Schema: "public", Table: table, Column: column,
+		ReferencedSchema: "public", ReferencedTable: referenced, ReferencedColumn: "id",
+	}
+}
+
+// focusSchema is a small graph with a cycle (users and teams reference each
+// other) and a self-reference (employees.manager_id):
+//
+//	order_items -> orders -> users <-> teams -> orgs
+//	employees -> employees
+func focusSchema() *Schema {
+	table := func(name string, columns ...string) Table {
+		t := Table{Name: name, Schema: "public", PrimaryKeys: []string{"id"}}
+		t.Columns = append(t.Columns, Column{Name: "id", Type: "integer", IsPrimaryKey: true})
+		for _, column := range columns {
+			t.Columns = append(t.Columns, Column{Name: column, Type: "integer"})
+		}
+		return t
+	}
+	return &Schema{
+		Database: "postgresql",
+		Tables: []Table{
+			table("orgs", "name"),
+			table("teams", "org_id", "lead_id"),
+			table("users", "name", "team_id"),
+			table("orders", "user_id", "total"),
+			table("order_items", "order_id", "quantity"),
+			table("employees", "manager_id"),
+		},
+		Views: []View{
+			{Name: "active_users", Schema: "public", Definition: "SELECT * FROM users"},
+		},
+		ForeignKeys: []ForeignKey{
+			fk("teams", "org_id", "orgs"),
+			fk("teams", "lead_id", "users"),
+			fk("users", "team_id", "teams"),
+			fk("orders", "user_id", "users"),
+			fk("order_items", "order_id", "orders"),
+			fk("employees", "manager_id", "employees"),
+		},
+		Indexes: []Index{
+			{Name: "users_team_id_idx", Schema: "public", Table: "users", Columns: []string{"team_id"}},
+			{Name: "orders_user_id_idx", Schema: "public", Table: "orders", Columns: []string{"user_id"}},
+		},
+	}
+}
+
+func tableNames(s *Schema) []string {
+	var names []string
+	for _, table := range s.Tables {
+		names = append(names, table.Name)
+	}
+	return names
+}
+
+func TestFocus(t *testing.T) {
+	tests := []struct {
+		name      string
+		tables    []string
+		depth     int
+		direction Direction
+		want      []string
+		wantFKs   int
+		wantViews int
+	}{
+		{"depth 0", []string{"users"}, 0, Both, []string{"users"}, 0, 0},
+		{"depth 1", []string{"users"}, 1, Both, []string{"teams", "users", "orders"}, 3, 0},
+		{"depth 2", []string{"users"}, 2, Both, []string{"orgs", "teams", "users", "orders", "order_items"}, 5, 0},
+		{"out depth 1", []string{"users"}, 1, Outgoing, []string{"teams", "users"}, 2, 0},
+		{"out depth 2", []string{"orders"}, 2, Outgoing, []string{"teams", "users", "orders"}, 3, 0},
+		{"in depth 1", []string{"users"}, 1, Incoming, []string{"teams", "users", "orders"}, 3, 0},
+		{"in depth 2", []string{"users"}, 2, Incoming, []string{"teams", "users", "orders", "order_items"}, 4, 0},
+		{"in from a leaf", []string{"order_items"}, 3, Incoming, []string{"order_items"}, 0, 0},
+		{"cycle does not loop", []string{"teams"}, 10, Outgoing, []string{"orgs", "teams", "users"}, 3, 0},
+		{"self reference", []string{"employees"}, 2, Both, []string{"employees"}, 1, 0},
+		{"qualified and case-insensitive", []string{"PUBLIC.Orgs"}, 0, Both, []string{"orgs"}, 0, 0},
+		{"several start tables", []string{"orgs", "employees"}, 0, Both, []string{"orgs", "employees"}, 1, 0},
+		{"view", []string{"active_users"}, 2, Both, nil, 0, 1},
+		{"view and table", []string{"active_users", "orders"}, 1, Outgoing, []string{"users", "orders"}, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			focused, err := focusSchema().Focus(FocusOptions{Tables: tt.tables, Depth: tt.depth, Direction: tt.direction})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tableNames(focused); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tables = %v, want %v", got, tt.want)
+			}
+			if len(focused.ForeignKeys) != tt.wantFKs {
+				t.Errorf("foreign keys = %+v, want %d", focused.ForeignKeys, tt.wantFKs)
+			}
+			if len(focused.Views) != tt.wantViews {
+				t.Errorf("views = %+v, want %d", focused.Views, tt.wantViews)
+			}
+			kept := tableNames(focused)
+			for _, fk := range focused.ForeignKeys {
+				if !containsFold(kept, fk.Table) || !containsFold(kept, fk.ReferencedTable) {
+					t.Errorf("foreign key %s leaves the focused tables", fk.Name)
+				}
+			}
+			for _, index := range focused.Indexes {
+				if !containsFold(kept, index.Table) {
+					t.Errorf("index %s belongs to a dropped table", index.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestFocusUnknownTable(t *testing.T) {
+	_, err := focusSchema().Focus(FocusOptions{Tables: []string{"users", "nosuch"}, Depth: 1})
+	if err == nil {
+		t.Fatal("Focus() with an unknown table returned no error")
+	}
+	if !strings.Contains(err.Error(), "'nosuch' not found") {
+		t.Errorf("Focus() error = %v, want it to name the table", err)
+	}
+	if _, err := focusSchema().Focus(FocusOptions{Tables: []string{"audit.users"}}); err == nil {
+		t.Error("Focus() matched a table in another schema")
+	}
+}
+
+func TestFocusCollapseBoundary(t *testing.T) {
+	columns := func(s *Schema) map[string][]string {
+		result := make(map[string][]string)
+		for _, table := range s.Tables {
+			for _, col := range table.Columns {
+				result[table.Name] = append(result[table.Name], col.Name)
+			}
+			if table.Collapsed {
+				result[table.Name] = append(result[table.Name], "(collapsed)")
+			}
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		tables   []string
+		depth    int
+		collapse bool
+		want     map[string][]string
+	}{
+		{
+			// users still references teams, which is outside: it keeps only
+			// its key and the column orders points at. order_items has no
+			// reference leaving the neighbourhood and stays whole.
+			name: "boundary", tables: []string{"orders"}, depth: 1, collapse: true,
+			want: map[string][]string{
+				"users":       {"id", "(collapsed)"},
+				"orders":      {"id", "user_id", "total"},
+				"order_items": {"id", "order_id", "quantity"},
+			},
+		},
+		{
+			name: "disabled", tables: []string{"orders"}, depth: 1, collapse: false,
+			want: map[string][]string{
+				"users":       {"id", "name", "team_id"},
+				"orders":      {"id", "user_id", "total"},
+				"order_items": {"id", "order_id", "quantity"},
+			},
+		},
+		{
+			// The start table is never collapsed, even at depth 0.
+			name: "depth 0", tables: []string{"users"}, depth: 0, collapse: true,
+			want: map[string][]string{"users": {"id", "name", "team_id"}},
+		},
+		{
+			// teams references orgs and orders is referenced by order_items,
+			// both outside. teams keeps the columns of its cycle with users.
+			name: "cycle", tables: []string{"users"}, depth: 1, collapse: true,
+			want: map[string][]string{
+				"teams":  {"id", "lead_id", "(collapsed)"},
+				"users":  {"id", "name", "team_id"},
+				"orders": {"id", "user_id", "(collapsed)"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			focused, err := focusSchema().Focus(FocusOptions{
+				Tables: tt.tables, Depth: tt.depth, Direction: Both, CollapseBoundary: tt.collapse,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := columns(focused); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("columns = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDirection(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Direction
+		wantErr bool
+	}{
+		{"", Both, false},
+		{"both", Both, false},
+		{"OUT", Outgoing, false},
+		{"in", Incoming, false},
+		{"sideways", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDirection(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseDirection(%q) = %q, %v, want %q (error %v)", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -8,6 +8,7 @@ type Schema struct {
     Views       []View       `json:"views"`
     ForeignKeys []ForeignKey `json:"foreign_keys"`
     Indexes     []Index      `json:"indexes"`
+    Enums       []Enum       `json:"enums,omitempty"`
     GeneratedAt time.Time    `json:"generated_at"`
 }
 
@@ -18,6 +19,10 @@ type Table struct {
     Columns     []Column `json:"columns"`
     PrimaryKeys []string `json:"primary_keys"`
     Comment     string   `json:"comment"`
+    Collapsed   bool     `json:"collapsed,omitempty"`
+    // RowEstimate is the number of rows according to the database
+    // statistics, or nil when they are not available.
+    RowEstimate *int64 `json:"row_estimate,omitempty"`
 }
 
 type View struct {
@@ -43,19 +48,59 @@ type Column struct {
 
 type ForeignKey struct {
     Name             string `json:"name"`
+    Schema           string `json:"schema"`
     Table            string `json:"table"`
     Column           string `json:"column"`
+    ReferencedSchema string `json:"referenced_schema"`
     ReferencedTable  string `json:"referenced_table"`
     ReferencedColumn string `json:"referenced_column"`
     OnUpdate         string `json:"on_update"`
     OnDelete         string `json:"on_delete"`
+    // Ordinal numbers the foreign keys of a table from 1, for databases
+    // that leave them unnamed; the columns of a composite key share it. It
+    // is 0 when the name identifies the constraint.
+    Ordinal int `json:"ordinal,omitempty"`
 }
 
 type Index struct {
     Name      string   `json:"name"`
+    Schema    string   `json:"schema,omitempty"`
     Table     string   `json:"table"`
     Columns   []string `json:"columns"`
     IsUnique  bool     `json:"is_unique"`
     IsPrimary bool     `json:"is_primary"`
     Type      string   `json:"type"`
+}
+
+// Enum is a user-defined enumerated type. Columns of the type carry its name
+// as their Type.
+type Enum struct {
+    Name    string   `json:"name"`
+    Schema  string   `json:"schema"`
+    Values  []string `json:"values"`
+    Comment string   `json:"comment,omitempty"`
+}
+
+// Qualifier returns a function that names objects for display. Names are
+// prefixed with their schema only when the objects span several schemas, so
+// single-schema output stays unchanged while identically named tables in
+// different schemas remain distinct.
+func (s *Schema) Qualifier() func(schemaName, name string) string {
+    namespaces := make(map[string]bool)
+    for _, table := range s.Tables {
+        namespaces[table.Schema] = true
+    }
+    for _, view := range s.Views {
+        namespaces[view.Schema] = true
+    }
+
+    if len(namespaces) <= 1 {
+        return func(_, name string) string { return name }
+    }
+    return func(schemaName, name string) string {
+        if schemaName == "" {
+            return name
+        }
+        return schemaName + "." + name
+    }
 }
\ No newline at end of file